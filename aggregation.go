@@ -4,107 +4,933 @@
 package notify
 
 import (
+	"cmp"
+	"container/list"
 	"context"
-	"maps"
-	"reflect"
+	"iter"
+	"math"
+	"math/rand/v2"
 	"slices"
 	"sync"
+	"weak"
 )
 
-// An UntypedVar is returned from [Aggregation.Choose].
+// An UntypedVar is returned from [Aggregation.Choose]. It is
+// implemented by [Var] and by [Aggregation].
 type UntypedVar interface {
-	notifyLocked()
+	// Closed returns true if the variable has been closed.
+	Closed() bool
+
+	arm(entry *aggEntry) bool
+	changed() <-chan struct{}
+	disarm(entry *aggEntry)
+	// readLock acquires a read lock and returns the current value. The
+	// returned bool will be false if the implementation has no value.
+	readLock() (value any, unlock func(), ok bool)
+}
+
+// weakVar is implemented by weakRef.
+type weakVar interface {
+	// resolve returns nil if the variable has been garbage-collected.
+	resolve() UntypedVar
+}
+
+// weakRef is a comparable, weak reference to a Var.
+type weakRef[T any] struct {
+	ptr weak.Pointer[Var[T]]
+}
+
+func (r weakRef[T]) resolve() UntypedVar {
+	if v := r.ptr.Value(); v != nil {
+		return v
+	}
+	return nil
+}
+
+// An AggregationOption customizes an Aggregation. Options are passed to
+// [NewAggregation].
+type AggregationOption func(*Aggregation)
+
+// Eviction determines what happens when a variable is registered with
+// an Aggregation that has reached the limit set by [WithLimit].
+type Eviction int
+
+const (
+	// EvictionReject discards the newly-registered variable.
+	EvictionReject Eviction = iota
+	// EvictionLRU de-registers the variable that was least recently
+	// registered or chosen.
+	EvictionLRU
+)
+
+// OnEvict sets a callback that is invoked with each variable that is
+// evicted or rejected because of the limit set by [WithLimit]. The
+// callback is invoked while the Aggregation's lock is held and must not
+// access the Aggregation.
+func OnEvict(fn func(UntypedVar)) AggregationOption {
+	return func(a *Aggregation) { a.onEvict = fn }
+}
+
+// WithLimit caps the number of variables that may be registered with
+// the Aggregation. This provides a safety valve when variables are
+// registered on behalf of untrusted clients. Re-registering a variable
+// does not count against the limit.
+func WithLimit(n int, policy Eviction) AggregationOption {
+	return func(a *Aggregation) {
+		a.eviction = policy
+		a.limit = n
+	}
+}
+
+// A DispatchOption customizes [Aggregation.OnUpdate].
+type DispatchOption func(*dispatchConfig)
+
+type dispatchConfig struct {
+	concurrency int
+	rearm       bool
+}
+
+// Concurrency sets the number of goroutines that will invoke the
+// callback. The default is one.
+func Concurrency(n int) DispatchOption {
+	return func(cfg *dispatchConfig) { cfg.concurrency = n }
+}
+
+// Rearm causes each variable to remain registered after it has been
+// dispatched, as though it had been added with [AggregateSticky]. The
+// variable is re-armed before the callback is invoked, so with
+// [Concurrency] greater than one, the callback may be invoked
+// concurrently for the same variable.
+func Rearm() DispatchOption {
+	return func(cfg *dispatchConfig) { cfg.rearm = true }
 }
 
 // An Aggregation allows an arbitrary number of variables, of
 // potentially heterogeneous types, to be selected on.
+//
+// An Aggregation may itself be registered with another Aggregation by
+// calling [Aggregation.Add]. The inner Aggregation is considered to have
+// changed whenever it has changed variables to be chosen, so it should
+// be drained before being re-registered. Aggregations must not be
+// registered with one another in a cycle.
+//
+// Each registered variable signals the Aggregation directly when it
+// changes, so the cost of a wakeup does not depend on the number of
+// registered variables.
 type Aggregation struct {
+	eviction Eviction         // See WithLimit.
+	limit    int              // See WithLimit.
+	onEvict  func(UntypedVar) // See OnEvict.
+
 	mu struct {
 		sync.RWMutex
-		m map[UntypedVar]<-chan struct{}
+		lru           *list.List // Of *aggEntry, if limit is set.
+		m             map[UntypedVar]*aggEntry
+		ordered       bool          // See chooseOrderedLocked.
+		registered    chan struct{} // Closed when a variable is added.
+		registrations uint64        // See AggregationStats.
+		weak          map[weakVar]*aggEntry
+	}
+	// signal is acquired by variables while they hold their own locks,
+	// so no other lock may be acquired while it is held.
+	signal struct {
+		sync.Mutex
+		blocked int                            // The number of blocked calls to wait.
+		outer   map[*aggEntry]struct{}         // Registrations in other Aggregations.
+		ready   []*aggEntry                    // Entries whose variables have changed.
+		waiters map[<-chan struct{}]*aggWaiter // Keyed by ctx.Done(); see Updated.
+		wake    chan struct{}                  // Closed while ready is non-empty.
+		wakeups uint64                         // See AggregationStats.
 	}
 }
 
+// AggregationStats are returned from [Aggregation.Stats].
+type AggregationStats struct {
+	// Pending is the number of changes that are waiting to be chosen.
+	// This may include variables registered with AggregateWeak that
+	// have since been collected, which will be discarded by Choose.
+	Pending int
+	// Registrations is the total number of times that a variable has
+	// been registered with the Aggregation.
+	Registrations uint64
+	// Waiters is the number of channels returned from Updated that have
+	// yet to be closed, plus the number of goroutines blocked in
+	// methods such as Wait.
+	Waiters int
+	// Wakeups is the total number of changes that have been signaled by
+	// registered variables.
+	Wakeups uint64
+}
+
+// aggEntry records the registration of a variable. An entry is
+// retained by its variable until the variable changes, at which point
+// the entry is added to the Aggregation's ready list.
+type aggEntry struct {
+	agg      *Aggregation
+	elem     *list.Element // See WithLimit.
+	key      any           // See AggregateKeyed.
+	priority int           // See AggregatePriority.
+	ready    bool          // In the ready list; guarded by the signal lock.
+	sticky   bool          // See AggregateSticky.
+	weight   float64       // See AggregateWeighted.
+	strong   UntypedVar    // Nil if weakly aggregated.
+	weak     weakVar       // Nil if strongly aggregated.
+}
+
+// fire is called by the variable, with its lock held, when it changes.
+func (e *aggEntry) fire() {
+	s := &e.agg.signal
+	s.Lock()
+	defer s.Unlock()
+	if len(s.ready) == 0 {
+		e.agg.signalLocked()
+	}
+	s.ready = append(s.ready, e)
+	e.ready = true
+	s.wakeups++
+}
+
+// aggWaiter implements [Aggregation.Updated] without a goroutine.
+type aggWaiter struct {
+	ch   chan struct{}
+	once sync.Once
+	stop func() bool // Unregisters from the context.
+}
+
+// close closes the channel, if it has not already been closed.
+func (w *aggWaiter) close() {
+	w.once.Do(func() { close(w.ch) })
+}
+
 // NewAggregation constructs an Aggregation.
-func NewAggregation() *Aggregation {
+func NewAggregation(opts ...AggregationOption) *Aggregation {
 	agg := &Aggregation{}
-	agg.mu.m = make(map[UntypedVar]<-chan struct{})
+	for _, opt := range opts {
+		opt(agg)
+	}
+	if agg.limit > 0 {
+		agg.mu.lru = list.New()
+	}
+	agg.mu.m = make(map[UntypedVar]*aggEntry)
+	agg.mu.registered = make(chan struct{})
+	agg.mu.weak = make(map[weakVar]*aggEntry)
+	agg.signal.outer = make(map[*aggEntry]struct{})
+	agg.signal.waiters = make(map[<-chan struct{}]*aggWaiter)
+	agg.signal.wake = make(chan struct{})
 	return agg
 }
 
+// Add registers the variables with the Aggregation without sampling
+// their current values. This is useful for re-registering a variable
+// returned from [Aggregation.Choose]. Closed variables are ignored.
+func (a *Aggregation) Add(vars ...UntypedVar) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, v := range vars {
+		a.registerLocked(v, &aggEntry{agg: a, strong: v})
+	}
+}
+
 // Aggregate adds the variable to the [Aggregation] and returns the
-// current value of the variable.
+// current value of the variable. A closed variable will not be added,
+// since it will never change again.
 //
 // This should be a method whenever Go supports generic methods.
 func Aggregate[T any](agg *Aggregation, v *Var[T]) T {
 	agg.mu.Lock()
 	defer agg.mu.Unlock()
 
-	ret, ch := v.Get()
-	agg.mu.m[v] = ch
+	// Register before sampling, so that no change will be missed.
+	agg.registerLocked(v, &aggEntry{agg: agg, strong: v})
 
-	return ret
+	return v.Load()
 }
 
-// Choose selects one aggregated variable at random from the variables
-// that have changed since the last time [Aggregate] was called. If the
-// Aggregation is empty or no variables have changed, the returned
-// bool will be false.
+// AggregateKeyed adds the variable to the [Aggregation] and returns the
+// current value of the variable. The key is returned alongside the
+// variable by [Aggregation.ChooseKeyed] and [Aggregation.WaitKeyed], so
+// that callers need not maintain a separate mapping from variables to
+// the logical entities that they represent. Variables added by other
+// means have a nil key.
+//
+// This should be a method whenever Go supports generic methods.
+func AggregateKeyed[T any](agg *Aggregation, v *Var[T], key any) T {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	agg.registerLocked(v, &aggEntry{agg: agg, key: key, strong: v})
+
+	return v.Load()
+}
+
+// AggregatePriority adds the variable to the [Aggregation] and returns
+// the current value of the variable. When several variables have
+// changed, [Aggregation.Choose] will prefer those with a higher
+// priority. Variables added by other means have a priority of zero, so
+// a negative priority may be used to defer routine work. See also
+// [AggregateWeighted].
+//
+// This should be a method whenever Go supports generic methods.
+func AggregatePriority[T any](agg *Aggregation, v *Var[T], priority int) T {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	if agg.registerLocked(v, &aggEntry{agg: agg, priority: priority, strong: v}) {
+		agg.mu.ordered = true
+	}
+
+	return v.Load()
+}
+
+// AggregateSticky adds the variable to the [Aggregation] and returns the
+// current value of the variable. Unlike [Aggregate], the variable will
+// be re-armed automatically when it is returned from
+// [Aggregation.Choose], so that long-lived dispatch loops need not
+// re-aggregate it. A sticky variable is removed from the Aggregation
+// once it has been closed.
+//
+// This should be a method whenever Go supports generic methods.
+func AggregateSticky[T any](agg *Aggregation, v *Var[T]) T {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	agg.registerLocked(v, &aggEntry{agg: agg, sticky: true, strong: v})
+
+	return v.Load()
+}
+
+// AggregateWeak adds the variable to the [Aggregation] and returns the
+// current value of the variable. Unlike [Aggregate], the Aggregation
+// will hold only a weak reference to the variable. If the variable is
+// garbage-collected, it will be silently removed from the Aggregation.
+// This is useful for registries of short-lived variables which would
+// otherwise require explicit removal.
+//
+// This should be a method whenever Go supports generic methods.
+func AggregateWeak[T any](agg *Aggregation, v *Var[T]) T {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	agg.registerLocked(v, &aggEntry{agg: agg, weak: weakRef[T]{weak.Make(v)}})
+
+	return v.Load()
+}
+
+// AggregateWeighted adds the variable to the [Aggregation] and returns
+// the current value of the variable. When several variables of equal
+// priority have changed, [Aggregation.Choose] will pick among them at
+// random, in proportion to their weights. This allows processing to be
+// biased towards some variables without starving the others. Variables
+// added by other means, or with a non-positive weight, have a weight of
+// one.
+//
+// This should be a method whenever Go supports generic methods.
+func AggregateWeighted[T any](agg *Aggregation, v *Var[T], weight float64) T {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	if agg.registerLocked(v, &aggEntry{agg: agg, strong: v, weight: weight}) {
+		agg.mu.ordered = true
+	}
+
+	return v.Load()
+}
+
+// All returns an iterator over the registered variables. The variables
+// are collected when iteration begins, so the loop body may safely
+// modify the Aggregation. Variables that have changed, but which have
+// not yet been chosen, are still registered.
+func (a *Aggregation) All() iter.Seq[UntypedVar] {
+	return func(yield func(UntypedVar) bool) {
+		a.mu.RLock()
+		vars := make([]UntypedVar, 0, len(a.mu.m)+len(a.mu.weak))
+		for v := range a.mu.m {
+			vars = append(vars, v)
+		}
+		for k := range a.mu.weak {
+			if v := k.resolve(); v != nil {
+				vars = append(vars, v)
+			}
+		}
+		a.mu.RUnlock()
+
+		for _, v := range vars {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Changed returns an iterator that yields aggregated variables as they
+// change, until the context has been canceled. Each variable is re-armed
+// before it is yielded, so that changes made while the loop body is
+// executing will not be missed. A closed variable is yielded once and
+// is then removed from the Aggregation. If the Aggregation is empty,
+// the iterator will block until a variable is registered or the
+// context is canceled.
+func (a *Aggregation) Changed(ctx context.Context) iter.Seq[UntypedVar] {
+	return func(yield func(UntypedVar) bool) {
+		for {
+			found, _, err := a.wait(ctx, true)
+			if err != nil || !yield(found) {
+				return
+			}
+		}
+	}
+}
+
+// Choose selects one aggregated variable from the variables that have
+// changed since the last time [Aggregate] was called. If any variables
+// were added with [AggregatePriority], a variable with the highest
+// priority will be chosen. Variables of equal priority are chosen in
+// proportion to any weights given to [AggregateWeighted]. If the
+// Aggregation is empty or no variables have changed, the returned bool
+// will be false.
 func (a *Aggregation) Choose() (UntypedVar, bool) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
+	found, _, ok := a.choose(false)
+	return found, ok
+}
 
-	for k, v := range a.mu.m {
-		select {
-		case <-v:
-			delete(a.mu.m, k)
-			return k, true
-		default:
+// ChooseKeyed is analogous to [Aggregation.Choose], but also returns
+// the key that was provided to [AggregateKeyed].
+func (a *Aggregation) ChooseKeyed() (UntypedVar, any, bool) {
+	return a.choose(false)
+}
+
+// Closed implements [UntypedVar]. An Aggregation is never closed.
+func (a *Aggregation) Closed() bool {
+	return false
+}
+
+// Contains returns true if the variable is registered with the
+// Aggregation. A variable is no longer registered once it has been
+// returned from [Aggregation.Choose], unless it was added with
+// [AggregateSticky].
+func (a *Aggregation) Contains(v UntypedVar) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if _, ok := a.mu.m[v]; ok {
+		return true
+	}
+	for k := range a.mu.weak {
+		if k.resolve() == v {
+			return true
 		}
 	}
+	return false
+}
 
-	return nil, false
+// Drain returns every aggregated variable that has changed, as though
+// [Aggregation.Choose] were called repeatedly. The variables are
+// de-registered atomically, so that a concurrent registration will not
+// be drained by this call. The returned slice will be empty if no
+// variables have changed, and is ordered by descending priority if any
+// variables were added with [AggregatePriority].
+func (a *Aggregation) Drain() []UntypedVar {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var ret []UntypedVar
+	a.chooseLocked(false, func(found UntypedVar, _ any) bool {
+		ret = append(ret, found)
+		return true
+	})
+	return ret
 }
 
-// Len returns the number of aggregated variables.
+// Len returns the number of aggregated variables. Weakly-aggregated
+// variables that have been garbage-collected are not counted, and will
+// be removed from the Aggregation.
 func (a *Aggregation) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lenLocked()
+}
+
+// OnUpdate invokes the callback for each aggregated variable as it
+// changes, until the context has been canceled. Dispatched variables are
+// removed from the Aggregation, unless the [Rearm] option is used, and
+// may be re-registered by the callback. This method blocks until the
+// context has been canceled and all invocations of the callback have
+// returned.
+func (a *Aggregation) OnUpdate(ctx context.Context, fn func(UntypedVar), opts ...DispatchOption) {
+	cfg := dispatchConfig{concurrency: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var wg sync.WaitGroup
+	for range max(cfg.concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				found, _, err := a.wait(ctx, cfg.rearm)
+				if err != nil {
+					return
+				}
+				fn(found)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Snapshot returns the current values of all aggregated variables,
+// keyed by variable. The values are read while the locks of all
+// variables are held and while no [Batch] is being committed, so the
+// Snapshot is a consistent, point-in-time view of the variables.
+// Aggregations that are registered with this Aggregation are not
+// included.
+func (a *Aggregation) Snapshot() map[UntypedVar]any {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
-	return len(a.mu.m)
+
+	vars := make([]UntypedVar, 0, len(a.mu.m)+len(a.mu.weak))
+	for v := range a.mu.m {
+		vars = append(vars, v)
+	}
+	for k := range a.mu.weak {
+		if v := k.resolve(); v != nil {
+			vars = append(vars, v)
+		}
+	}
+
+	// See batchMu.
+	batchMu.Lock()
+	defer batchMu.Unlock()
+	ret := make(map[UntypedVar]any, len(vars))
+	for _, v := range vars {
+		if value, unlock, ok := v.readLock(); ok {
+			defer unlock()
+			ret[v] = value
+		}
+	}
+	return ret
+}
+
+// Stats returns a point-in-time summary of the Aggregation's activity.
+func (a *Aggregation) Stats() AggregationStats {
+	a.mu.RLock()
+	registrations := a.mu.registrations
+	a.mu.RUnlock()
+
+	a.signal.Lock()
+	defer a.signal.Unlock()
+	return AggregationStats{
+		Pending:       len(a.signal.ready),
+		Registrations: registrations,
+		Waiters:       len(a.signal.waiters) + a.signal.blocked,
+		Wakeups:       a.signal.wakeups,
+	}
 }
 
 // Updated returns a channel that will be closed if any variable has
 // changed since the last time [Aggregate] was called on it or the
 // context is cancelled. The updated variable is retrieved by calling
-// [Aggregation.Choose].
+// [Aggregation.Choose]. The channel may be closed spuriously if a
+// variable registered with [AggregateWeak] is collected before being
+// chosen.
+//
+// No goroutine is started by this method, so it may be called
+// repeatedly with a long-lived context. Calls which use the same
+// context share a channel until the next change. Callers that have no
+// context may pass [context.Background] to receive a channel that is
+// shared by all such callers.
 func (a *Aggregation) Updated(ctx context.Context) <-chan struct{} {
-	a.mu.RLock()
-	toWatch := slices.Collect(maps.Values(a.mu.m))
-	a.mu.RUnlock()
+	a.signal.Lock()
+	defer a.signal.Unlock()
+	done := ctx.Done()
+	if done == nil || len(a.signal.ready) > 0 {
+		return a.signal.wake
+	}
+	if w, ok := a.signal.waiters[done]; ok {
+		return w.ch
+	}
 
-	cases := make([]reflect.SelectCase, len(toWatch)+1)
-	cases[0] = reflect.SelectCase{
-		Chan: reflect.ValueOf(ctx.Done()),
-		Dir:  reflect.SelectRecv,
+	w := &aggWaiter{ch: make(chan struct{})}
+	w.stop = context.AfterFunc(ctx, func() {
+		a.signal.Lock()
+		if a.signal.waiters[done] == w {
+			delete(a.signal.waiters, done)
+		}
+		a.signal.Unlock()
+		w.close()
+	})
+	a.signal.waiters[done] = w
+	return w.ch
+}
+
+// ValueOf returns the current value of a variable returned from
+// [Aggregation.Choose] or similar methods. If the variable is not a
+// *Var[T], the returned bool will be false. This allows callers to
+// avoid a type switch when the type of a variable is known.
+func ValueOf[T any](v UntypedVar) (T, bool) {
+	if typed, ok := v.(*Var[T]); ok {
+		return typed.Load(), true
 	}
+	var zero T
+	return zero, false
+}
 
-	ret := make(chan struct{})
-	for i, ch := range toWatch {
-		select {
-		case <-ch:
-			close(ret)
-			return ret
-		default:
-			cases[i+1] = reflect.SelectCase{
-				Chan: reflect.ValueOf(ch),
-				Dir:  reflect.SelectRecv,
+// Wait blocks until a registered variable has changed and returns it,
+// as though by [Aggregation.Choose]. Variables that are registered while
+// Wait is blocked will also be observed. If the context is canceled
+// before any variable has changed, the context's error will be
+// returned.
+func (a *Aggregation) Wait(ctx context.Context) (UntypedVar, error) {
+	found, _, err := a.wait(ctx, false)
+	return found, err
+}
+
+// WaitKeyed is analogous to [Aggregation.Wait], but also returns the
+// key that was provided to [AggregateKeyed].
+func (a *Aggregation) WaitKeyed(ctx context.Context) (UntypedVar, any, error) {
+	return a.wait(ctx, false)
+}
+
+// arm implements [UntypedVar]. The entry will be fired immediately if
+// the Aggregation already has changed variables.
+func (a *Aggregation) arm(entry *aggEntry) bool {
+	a.signal.Lock()
+	defer a.signal.Unlock()
+	if len(a.signal.ready) > 0 {
+		entry.fire()
+	} else {
+		a.signal.outer[entry] = struct{}{}
+	}
+	return true
+}
+
+// changed implements [UntypedVar].
+func (a *Aggregation) changed() <-chan struct{} {
+	return a.wake()
+}
+
+// choose implements [Aggregation.Choose]. If rearm is true, the chosen
+// variable will remain registered, as though it had been added with
+// [AggregateSticky].
+func (a *Aggregation) choose(rearm bool) (UntypedVar, any, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var ret UntypedVar
+	var key any
+	a.chooseLocked(rearm, func(found UntypedVar, foundKey any) bool {
+		ret, key = found, foundKey
+		return false
+	})
+	return ret, key, ret != nil
+}
+
+// chooseLocked passes changed variables to the callback, which returns
+// false to stop the iteration. Each variable is de-registered, or
+// re-armed if it was aggregated with [AggregateSticky] or rearm is
+// true, before being passed to the callback.
+func (a *Aggregation) chooseLocked(rearm bool, fn func(found UntypedVar, key any) bool) {
+	if a.mu.ordered {
+		a.chooseOrderedLocked(rearm, fn)
+		return
+	}
+
+	for {
+		entry, ok := a.pop()
+		if !ok {
+			return
+		}
+		if found := a.resolveLocked(entry); found != nil {
+			a.releaseLocked(entry, found, rearm)
+			if !fn(found, entry.key) {
+				return
 			}
 		}
 	}
+}
 
-	go func() {
-		defer close(ret)
-		reflect.Select(cases)
-	}()
-	return ret
+// chooseOrderedLocked implements chooseLocked once a variable has been
+// added with [AggregatePriority] or [AggregateWeighted]. All changed
+// variables must be found before the callback can be invoked in
+// priority order. Variables of equal priority are ordered by weighted
+// random sampling, where each variable is assigned the rank u^(1/w)
+// for a uniformly-random u.
+func (a *Aggregation) chooseOrderedLocked(rearm bool, fn func(found UntypedVar, key any) bool) {
+	a.signal.Lock()
+	ready := a.signal.ready
+	a.signal.ready = nil
+	if len(ready) > 0 {
+		a.signal.wake = make(chan struct{})
+	}
+	for _, entry := range ready {
+		entry.ready = false
+	}
+	a.signal.Unlock()
+
+	ranks := make(map[*aggEntry]float64, len(ready))
+	for _, entry := range ready {
+		weight := entry.weight
+		if weight <= 0 {
+			weight = 1
+		}
+		ranks[entry] = math.Pow(rand.Float64(), 1/weight)
+	}
+	slices.SortFunc(ready, func(a, b *aggEntry) int {
+		if c := cmp.Compare(b.priority, a.priority); c != 0 {
+			return c
+		}
+		return cmp.Compare(ranks[b], ranks[a])
+	})
+
+	for i, entry := range ready {
+		found := a.resolveLocked(entry)
+		if found == nil {
+			continue
+		}
+		a.releaseLocked(entry, found, rearm)
+		if !fn(found, entry.key) {
+			// Return the remaining entries to the ready list.
+			if rest := ready[i+1:]; len(rest) > 0 {
+				a.signal.Lock()
+				if len(a.signal.ready) == 0 {
+					a.signalLocked()
+				}
+				for _, entry := range rest {
+					entry.ready = true
+				}
+				a.signal.ready = append(rest, a.signal.ready...)
+				a.signal.Unlock()
+			}
+			return
+		}
+	}
+}
+
+// disarm implements [UntypedVar].
+func (a *Aggregation) disarm(entry *aggEntry) {
+	a.signal.Lock()
+	defer a.signal.Unlock()
+	delete(a.signal.outer, entry)
+}
+
+// evictLocked de-registers the least-recently used variable.
+func (a *Aggregation) evictLocked() {
+	entry := a.mu.lru.Front().Value.(*aggEntry)
+	a.forgetLocked(entry)
+	var found UntypedVar
+	if entry.weak == nil {
+		found = entry.strong
+	} else {
+		found = entry.weak.resolve()
+	}
+	if found != nil {
+		found.disarm(entry)
+		a.evicted(found)
+	}
+}
+
+// evicted invokes the callback set by [OnEvict].
+func (a *Aggregation) evicted(v UntypedVar) {
+	if a.onEvict != nil {
+		a.onEvict(v)
+	}
+}
+
+// forgetLocked removes the entry from the Aggregation. If the entry's
+// variable has changed, the entry is also removed from the ready list,
+// so that waiters are not woken for a change that Choose would discard.
+func (a *Aggregation) forgetLocked(entry *aggEntry) {
+	if entry.weak == nil {
+		if a.mu.m[entry.strong] == entry {
+			delete(a.mu.m, entry.strong)
+		}
+	} else if a.mu.weak[entry.weak] == entry {
+		delete(a.mu.weak, entry.weak)
+	}
+	if entry.elem != nil {
+		a.mu.lru.Remove(entry.elem)
+		entry.elem = nil
+	}
+
+	a.signal.Lock()
+	defer a.signal.Unlock()
+	if !entry.ready {
+		return
+	}
+	entry.ready = false
+	a.signal.ready = slices.DeleteFunc(a.signal.ready, func(e *aggEntry) bool { return e == entry })
+	if len(a.signal.ready) == 0 {
+		a.signal.ready = nil
+		a.signal.wake = make(chan struct{})
+	}
+}
+
+// lenLocked implements [Aggregation.Len].
+func (a *Aggregation) lenLocked() int {
+	for _, entry := range a.mu.weak {
+		if entry.weak.resolve() == nil {
+			a.forgetLocked(entry)
+		}
+	}
+	return len(a.mu.m) + len(a.mu.weak)
+}
+
+// pop removes the oldest entry from the ready list.
+func (a *Aggregation) pop() (*aggEntry, bool) {
+	a.signal.Lock()
+	defer a.signal.Unlock()
+	if len(a.signal.ready) == 0 {
+		return nil, false
+	}
+	ret := a.signal.ready[0]
+	ret.ready = false
+	a.signal.ready[0] = nil
+	a.signal.ready = a.signal.ready[1:]
+	if len(a.signal.ready) == 0 {
+		a.signal.ready = nil
+		a.signal.wake = make(chan struct{})
+	}
+	return ret, true
+}
+
+// readLock implements [UntypedVar]. An Aggregation has no value.
+func (a *Aggregation) readLock() (any, func(), bool) {
+	return nil, nil, false
+}
+
+// registerLocked arms the entry and stores it in the Aggregation,
+// replacing any previous registration of the variable. This method
+// returns false if the variable has been closed or was rejected
+// because of the limit set by [WithLimit].
+func (a *Aggregation) registerLocked(v UntypedVar, entry *aggEntry) bool {
+	var prev *aggEntry
+	if entry.weak == nil {
+		prev = a.mu.m[v]
+	} else {
+		prev = a.mu.weak[entry.weak]
+	}
+	if v.Closed() {
+		return false
+	}
+	if prev == nil && a.limit > 0 {
+		for a.lenLocked() >= a.limit {
+			if a.eviction == EvictionReject {
+				a.evicted(v)
+				return false
+			}
+			a.evictLocked()
+		}
+	}
+	if !v.arm(entry) {
+		return false
+	}
+	if prev != nil {
+		v.disarm(prev)
+		a.forgetLocked(prev)
+	}
+	if entry.weak == nil {
+		a.mu.m[v] = entry
+	} else {
+		a.mu.weak[entry.weak] = entry
+	}
+	if a.mu.lru != nil {
+		entry.elem = a.mu.lru.PushBack(entry)
+	}
+	a.mu.registrations++
+	a.registeredLocked()
+	return true
+}
+
+// registeredLocked wakes any calls to [Aggregation.Wait].
+func (a *Aggregation) registeredLocked() {
+	close(a.mu.registered)
+	a.mu.registered = make(chan struct{})
+}
+
+// releaseLocked de-registers a variable that has been chosen, or
+// re-arms it if it was added with [AggregateSticky] or rearm is true.
+func (a *Aggregation) releaseLocked(entry *aggEntry, found UntypedVar, rearm bool) {
+	if (entry.sticky || rearm) && found.arm(entry) {
+		if entry.elem != nil {
+			a.mu.lru.MoveToBack(entry.elem)
+		}
+		return
+	}
+	a.forgetLocked(entry)
+}
+
+// resolveLocked returns the variable associated with an entry from the
+// ready list, or nil if the entry has been superseded or its variable
+// has been garbage-collected.
+func (a *Aggregation) resolveLocked(entry *aggEntry) UntypedVar {
+	if entry.weak == nil {
+		if a.mu.m[entry.strong] != entry {
+			return nil
+		}
+		return entry.strong
+	}
+	if a.mu.weak[entry.weak] != entry {
+		return nil
+	}
+	found := entry.weak.resolve()
+	if found == nil {
+		a.forgetLocked(entry)
+	}
+	return found
+}
+
+// signalLocked closes the wake channel and the channels of any calls to
+// Updated once the ready list becomes non-empty. Any Aggregations in
+// which this Aggregation is registered will also be signaled.
+func (a *Aggregation) signalLocked() {
+	close(a.signal.wake)
+	for _, w := range a.signal.waiters {
+		w.stop()
+		w.close()
+	}
+	clear(a.signal.waiters)
+	for entry := range a.signal.outer {
+		entry.fire()
+	}
+	clear(a.signal.outer)
+}
+
+// wait implements [Aggregation.Wait]. See also choose.
+func (a *Aggregation) wait(ctx context.Context, rearm bool) (UntypedVar, any, error) {
+	for {
+		// Acquire the channels before calling choose, so that a
+		// concurrent change or registration will not be missed.
+		a.mu.RLock()
+		registered := a.mu.registered
+		a.mu.RUnlock()
+		wake := a.wake()
+
+		if found, key, ok := a.choose(rearm); ok {
+			return found, key, nil
+		}
+
+		a.signal.Lock()
+		a.signal.blocked++
+		a.signal.Unlock()
+		select {
+		case <-wake:
+		case <-registered:
+		case <-ctx.Done():
+		}
+		a.signal.Lock()
+		a.signal.blocked--
+		a.signal.Unlock()
+
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// wake returns a channel that is closed while there are changed
+// variables to be chosen.
+func (a *Aggregation) wake() <-chan struct{} {
+	a.signal.Lock()
+	defer a.signal.Unlock()
+	return a.signal.wake
 }
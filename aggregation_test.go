@@ -5,6 +5,9 @@ package notify
 
 import (
 	"context"
+	"runtime"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
@@ -55,6 +58,36 @@ func TestAggregationDrain(t *testing.T) {
 	}
 }
 
+func TestAggregationDrainAll(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	r.Empty(agg.Drain())
+
+	vars := make([]*Var[int], 10)
+	for i := range vars {
+		vars[i] = VarOf(i)
+		Aggregate(agg, vars[i])
+	}
+	sticky := VarOf("sticky")
+	AggregateSticky(agg, sticky)
+	r.Empty(agg.Drain())
+
+	// Only the changed variables are drained.
+	for i := 0; i < len(vars); i += 2 {
+		vars[i].Set(-i)
+	}
+	sticky.Set("changed")
+	drained := agg.Drain()
+	r.Len(drained, len(vars)/2+1)
+	for i := 0; i < len(vars); i += 2 {
+		r.Contains(drained, UntypedVar(vars[i]))
+	}
+	r.Contains(drained, UntypedVar(sticky))
+	r.Equal(len(vars)/2+1, agg.Len())
+	r.Empty(agg.Drain())
+}
+
 func TestAggregationImmediate(t *testing.T) {
 	r := require.New(t)
 
@@ -90,3 +123,599 @@ func TestAggregationImmediate(t *testing.T) {
 	}
 	r.Equal(len(vars), count)
 }
+
+func TestAggregationAdd(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	a := VarOf(1)
+	b := VarOf("b")
+	agg.Add(a, b)
+	r.Equal(2, agg.Len())
+
+	a.Set(2)
+	found, ok := agg.Choose()
+	r.True(ok)
+	r.Same(a, found)
+	r.Equal(1, agg.Len())
+
+	// Re-registering the chosen variable should observe later changes.
+	agg.Add(found)
+	_, ok = agg.Choose()
+	r.False(ok)
+	a.Set(3)
+	found, ok = agg.Choose()
+	r.True(ok)
+	r.Same(a, found)
+}
+
+func TestAggregatePriority(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	routine := make([]*Var[int], 10)
+	for i := range routine {
+		routine[i] = VarOf(i)
+		Aggregate(agg, routine[i])
+	}
+	low := VarOf(-1)
+	r.Equal(-1, AggregatePriority(agg, low, -1))
+	shutdown := VarOf(false)
+	r.False(AggregatePriority(agg, shutdown, 10))
+
+	low.Set(-2)
+	for _, v := range routine {
+		v.Set(100)
+	}
+	shutdown.Set(true)
+
+	found, err := agg.Wait(t.Context())
+	r.NoError(err)
+	r.Same(shutdown, found)
+
+	// The remaining variables are drained in priority order.
+	drained := agg.Drain()
+	r.Len(drained, len(routine)+1)
+	r.Same(low, drained[len(drained)-1])
+	r.Zero(agg.Len())
+}
+
+func TestAggregateSticky(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	v := VarOf(1)
+	r.Equal(1, AggregateSticky(agg, v))
+	_, ok := agg.Choose()
+	r.False(ok)
+
+	// A sticky variable remains registered after being chosen.
+	for i := 2; i <= 4; i++ {
+		v.Set(i)
+		found, ok := agg.Choose()
+		r.True(ok)
+		r.Same(v, found)
+		r.Equal(1, agg.Len())
+		_, ok = agg.Choose()
+		r.False(ok)
+	}
+
+	// Closing the variable removes it once it has been chosen.
+	v.Close()
+	found, ok := agg.Choose()
+	r.True(ok)
+	r.Same(v, found)
+	r.Zero(agg.Len())
+}
+
+func TestAggregateWeak(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	kept := VarOf(1)
+	r.Equal(1, AggregateWeak(agg, kept))
+	func() {
+		dropped := VarOf(2)
+		r.Equal(2, AggregateWeak(agg, dropped))
+	}()
+
+	// The unreferenced variable should eventually disappear.
+	r.Eventually(func() bool {
+		runtime.GC()
+		return agg.Len() == 1
+	}, time.Minute, time.Millisecond)
+
+	kept.Set(3)
+	found, ok := agg.Choose()
+	r.True(ok)
+	r.Same(kept, found)
+	r.Zero(agg.Len())
+	runtime.KeepAlive(kept)
+}
+
+func TestAggregationClosed(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	v := VarOf(1)
+	Aggregate(agg, v)
+
+	// A closed variable is chosen once, but cannot be re-registered.
+	v.Close()
+	found, ok := agg.Choose()
+	r.True(ok)
+	r.True(found.Closed())
+	agg.Add(found)
+	r.Equal(1, Aggregate(agg, v))
+	r.Zero(agg.Len())
+}
+
+func TestValueOf(t *testing.T) {
+	r := require.New(t)
+
+	var v UntypedVar = VarOf(42)
+	i, ok := ValueOf[int](v)
+	r.True(ok)
+	r.Equal(42, i)
+
+	s, ok := ValueOf[string](v)
+	r.False(ok)
+	r.Empty(s)
+}
+
+func TestAggregationWait(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	a := VarOf(1)
+	Aggregate(agg, a)
+
+	// A variable which has already changed is returned immediately.
+	a.Set(2)
+	found, err := agg.Wait(t.Context())
+	r.NoError(err)
+	r.Same(a, found)
+
+	// Variables registered while waiting are observed.
+	b := VarOf("b")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		Aggregate(agg, b)
+		b.Set("bb")
+	}()
+	found, err = agg.Wait(t.Context())
+	r.NoError(err)
+	r.Same(b, found)
+
+	// An empty Aggregation waits for the context.
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	found, err = agg.Wait(ctx)
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.Nil(found)
+}
+
+func TestAggregationManyVars(t *testing.T) {
+	r := require.New(t)
+
+	// This exceeds the number of cases supported by reflect.Select.
+	const count = 100_000
+	agg := NewAggregation()
+	vars := make([]*Var[int], count)
+	for i := range vars {
+		vars[i] = VarOf(i)
+		Aggregate(agg, vars[i])
+	}
+	r.Equal(count, agg.Len())
+
+	updated := agg.Updated(t.Context())
+	r.False(Changed(updated))
+	vars[count-1].Set(-1)
+	<-updated
+
+	found, ok := agg.Choose()
+	r.True(ok)
+	r.Same(vars[count-1], found)
+	_, ok = agg.Choose()
+	r.False(ok)
+	r.False(Changed(agg.Updated(t.Context())))
+}
+
+func TestAggregationReregister(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	v := VarOf(1)
+	Aggregate(agg, v)
+	v.Set(2)
+
+	// Re-registering a changed variable discards the pending change.
+	r.Equal(2, Aggregate(agg, v))
+	_, ok := agg.Choose()
+	r.False(ok)
+	r.Equal(1, agg.Len())
+
+	v.Set(3)
+	found, ok := agg.Choose()
+	r.True(ok)
+	r.Same(v, found)
+}
+
+func TestAggregationUpdatedNoGoroutines(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	v := VarOf(1)
+	Aggregate(agg, v)
+
+	// Repeated calls with a long-lived context should not accumulate
+	// goroutines.
+	before := runtime.NumGoroutine()
+	chs := make([]<-chan struct{}, 1000)
+	for i := range chs {
+		chs[i] = agg.Updated(t.Context())
+	}
+	r.LessOrEqual(runtime.NumGoroutine(), before)
+	// Nor should they accumulate waiters.
+	agg.signal.Lock()
+	r.Len(agg.signal.waiters, 1)
+	agg.signal.Unlock()
+	r.Equal(chs[0], chs[len(chs)-1])
+
+	v.Set(2)
+	for _, ch := range chs {
+		<-ch
+	}
+	r.Len(agg.signal.waiters, 0)
+
+	found, ok := agg.Choose()
+	r.True(ok)
+	r.Same(v, found)
+
+	// Canceling the context closes the channel.
+	Aggregate(agg, v)
+	ctx, cancel := context.WithCancel(t.Context())
+	ch := agg.Updated(ctx)
+	r.False(Changed(ch))
+	cancel()
+	<-ch
+	r.Eventually(func() bool {
+		agg.signal.Lock()
+		defer agg.signal.Unlock()
+		return len(agg.signal.waiters) == 0
+	}, time.Second, time.Millisecond)
+
+	// Re-registering a changed variable before it is chosen does not
+	// leave a stale notification behind.
+	v.Set(3)
+	r.True(Changed(agg.Updated(t.Context())))
+	Aggregate(agg, v)
+	r.False(Changed(agg.Updated(t.Context())))
+	r.Zero(agg.Stats().Pending)
+	_, ok = agg.Choose()
+	r.False(ok)
+}
+
+func TestAggregationChanged(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	a := VarOf(0)
+	b := VarOf("")
+	Aggregate(agg, a)
+	Aggregate(agg, b)
+
+	go func() {
+		for i := 1; i <= 3; i++ {
+			a.Set(i)
+		}
+		b.Set("done")
+	}()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	for found := range agg.Changed(ctx) {
+		if value, ok := ValueOf[string](found); ok && value == "done" {
+			break
+		}
+	}
+	// The variables remain registered after being yielded.
+	r.Equal(2, agg.Len())
+
+	// Closed variables are removed.
+	a.Close()
+	for found := range agg.Changed(ctx) {
+		r.Same(a, found)
+		break
+	}
+	r.Equal(1, agg.Len())
+
+	// The iterator stops once the context is canceled.
+	cancel()
+	for range agg.Changed(ctx) {
+		r.Fail("should not yield")
+	}
+}
+
+func TestAggregationOnUpdate(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	vars := make([]*Var[int], 10)
+	for i := range vars {
+		vars[i] = VarOf(0)
+		Aggregate(agg, vars[i])
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	seen := VarOf(0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agg.OnUpdate(ctx, func(UntypedVar) {
+			_, _, _ = seen.Update(func(old int) (int, error) { return old + 1, nil })
+		}, Concurrency(4), Rearm())
+	}()
+
+	// Each variable remains registered, so every round is dispatched.
+	for round := 1; round <= 3; round++ {
+		for _, v := range vars {
+			v.Set(round)
+		}
+		_, err := seen.Wait(ctx, func(n int) bool { return n >= round*len(vars) })
+		r.NoError(err)
+	}
+	r.Equal(len(vars), agg.Len())
+
+	cancel()
+	<-done
+}
+
+func TestAggregationStats(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	a := VarOf(0)
+	b := VarOf(0)
+	Aggregate(agg, a)
+	Aggregate(agg, b)
+	r.Equal(AggregationStats{Registrations: 2}, agg.Stats())
+
+	a.Set(1)
+	b.Set(1)
+	r.Equal(AggregationStats{Pending: 2, Registrations: 2, Wakeups: 2}, agg.Stats())
+
+	r.Len(agg.Drain(), 2)
+	Aggregate(agg, a)
+	_ = agg.Updated(t.Context())
+	r.Equal(AggregationStats{Registrations: 3, Waiters: 1, Wakeups: 2}, agg.Stats())
+
+	// Blocked calls to Wait are counted.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = agg.Wait(t.Context())
+	}()
+	r.Eventually(func() bool {
+		return agg.Stats().Waiters == 2
+	}, time.Second, time.Millisecond)
+	a.Set(2)
+	<-done
+	r.Equal(AggregationStats{Registrations: 3, Wakeups: 3}, agg.Stats())
+}
+
+func TestAggregationNested(t *testing.T) {
+	r := require.New(t)
+
+	inner := NewAggregation()
+	v := VarOf(1)
+	Aggregate(inner, v)
+
+	outer := NewAggregation()
+	other := VarOf("other")
+	Aggregate(outer, other)
+	outer.Add(inner)
+	r.Equal(2, outer.Len())
+
+	// A change to the inner variable is visible to the outer Aggregation.
+	v.Set(2)
+	found, err := outer.Wait(t.Context())
+	r.NoError(err)
+	r.Same(inner, found)
+	r.Equal([]UntypedVar{v}, found.(*Aggregation).Drain())
+
+	// Registering an inner Aggregation with pending changes signals
+	// immediately.
+	Aggregate(inner, v)
+	v.Set(3)
+	outer.Add(inner)
+	found, ok := outer.Choose()
+	r.True(ok)
+	r.Same(inner, found)
+	r.False(found.Closed())
+}
+
+func TestAggregationSnapshot(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	a := VarOf(1)
+	b := VarOf("b")
+	Aggregate(agg, a)
+	AggregateWeak(agg, b)
+	agg.Add(NewAggregation())
+	r.Equal(map[UntypedVar]any{a: 1, b: "b"}, agg.Snapshot())
+
+	// Values committed by a Batch are observed together.
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 2; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			batch := BeginBatch()
+			BatchSet(batch, a, i)
+			BatchSet(batch, b, strconv.Itoa(i))
+			if err := batch.EndBatch(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for range 100 {
+		snap := agg.Snapshot()
+		if snap[a] != 1 {
+			r.Equal(strconv.Itoa(snap[a].(int)), snap[b])
+		}
+	}
+	close(stop)
+	<-done
+	runtime.KeepAlive(b)
+}
+
+func TestAggregateKeyed(t *testing.T) {
+	r := require.New(t)
+
+	type tenant string
+	agg := NewAggregation()
+	a := VarOf(1)
+	b := VarOf(2)
+	r.Equal(1, AggregateKeyed(agg, a, tenant("a")))
+	r.Equal(2, AggregateKeyed(agg, b, tenant("b")))
+
+	b.Set(3)
+	found, key, ok := agg.ChooseKeyed()
+	r.True(ok)
+	r.Same(b, found)
+	r.Equal(tenant("b"), key)
+	_, _, ok = agg.ChooseKeyed()
+	r.False(ok)
+
+	a.Set(4)
+	found, key, err := agg.WaitKeyed(t.Context())
+	r.NoError(err)
+	r.Same(a, found)
+	r.Equal(tenant("a"), key)
+
+	// Unkeyed variables have a nil key.
+	Aggregate(agg, a)
+	a.Set(5)
+	_, key, err = agg.WaitKeyed(t.Context())
+	r.NoError(err)
+	r.Nil(key)
+}
+
+func TestAggregationMembership(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	a := VarOf(1)
+	b := VarOf(2)
+	c := VarOf(3)
+	Aggregate(agg, a)
+	AggregateWeak(agg, b)
+	r.True(agg.Contains(a))
+	r.True(agg.Contains(b))
+	r.False(agg.Contains(c))
+	r.ElementsMatch([]UntypedVar{a, b}, slices.Collect(agg.All()))
+
+	// Chosen variables are no longer registered.
+	a.Set(4)
+	found, ok := agg.Choose()
+	r.True(ok)
+	r.Same(a, found)
+	r.False(agg.Contains(a))
+	r.Equal([]UntypedVar{b}, slices.Collect(agg.All()))
+	runtime.KeepAlive(b)
+}
+
+func TestAggregationLimit(t *testing.T) {
+	t.Run("lru", func(t *testing.T) {
+		r := require.New(t)
+
+		var evicted []UntypedVar
+		agg := NewAggregation(
+			WithLimit(2, EvictionLRU),
+			OnEvict(func(v UntypedVar) { evicted = append(evicted, v) }),
+		)
+		a, b, c := VarOf(1), VarOf(2), VarOf(3)
+		Aggregate(agg, a)
+		AggregateSticky(agg, b)
+
+		// Choosing a sticky variable counts as a use.
+		b.Set(4)
+		found, ok := agg.Choose()
+		r.True(ok)
+		r.Same(b, found)
+		// Re-registering does not count against the limit.
+		Aggregate(agg, a)
+		Aggregate(agg, a)
+		r.Empty(evicted)
+
+		// The least-recently used variable is b.
+		Aggregate(agg, c)
+		r.Equal([]UntypedVar{b}, evicted)
+		r.False(agg.Contains(b))
+		r.True(agg.Contains(a))
+		r.True(agg.Contains(c))
+
+		// An evicted variable no longer signals the Aggregation.
+		b.Set(5)
+		_, ok = agg.Choose()
+		r.False(ok)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		r := require.New(t)
+
+		var rejected []UntypedVar
+		agg := NewAggregation(
+			WithLimit(1, EvictionReject),
+			OnEvict(func(v UntypedVar) { rejected = append(rejected, v) }),
+		)
+		a, b := VarOf(1), VarOf(2)
+		Aggregate(agg, a)
+		r.Equal(2, Aggregate(agg, b))
+		r.Equal([]UntypedVar{b}, rejected)
+		r.Equal(1, agg.Len())
+		r.True(agg.Contains(a))
+
+		// Space is freed once a variable is chosen.
+		a.Set(3)
+		_, ok := agg.Choose()
+		r.True(ok)
+		Aggregate(agg, b)
+		r.True(agg.Contains(b))
+	})
+}
+
+func TestAggregateWeighted(t *testing.T) {
+	r := require.New(t)
+
+	agg := NewAggregation()
+	heavy := VarOf(0)
+	light := VarOf(0)
+
+	counts := make(map[UntypedVar]int)
+	const rounds = 2000
+	for range rounds {
+		AggregateWeighted(agg, heavy, 9)
+		Aggregate(agg, light)
+		heavy.Set(1)
+		light.Set(1)
+		found, ok := agg.Choose()
+		r.True(ok)
+		counts[found]++
+		r.Len(agg.Drain(), 1)
+	}
+
+	// The heavy variable should be chosen about 90% of the time, but
+	// the light variable must not be starved.
+	r.InDelta(0.9, float64(counts[heavy])/rounds, 0.05)
+	r.Positive(counts[light])
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"fmt"
+	"sync"
+)
+
+// batchMu serializes calls to [Batch.EndBatch], since acquiring the
+// locks of several variables in an arbitrary order could otherwise
+// deadlock.
+var batchMu sync.Mutex
+
+// A Batch stores values into multiple variables such that no reader
+// can observe any of the new values until all of them have been stored.
+// This is useful when several variables are populated from a single
+// external document, such as a configuration file, and readers should
+// not observe a partially-applied document. A Batch is constructed by
+// [BeginBatch], populated by [BatchSet], and committed by
+// [Batch.EndBatch].
+//
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	entries []batchEntry
+	index   map[UntypedVar]int
+}
+
+type batchEntry interface {
+	check() error
+	lock()
+	store()
+	unlock()
+}
+
+type batchSet[T any] struct {
+	next T
+	v    *Var[T]
+
+	// Populated by check.
+	resolved T
+	ok       bool
+}
+
+// check runs the variable's interceptors and validator, so that the
+// value to store is known before any variable is modified.
+func (s *batchSet[T]) check() error {
+	if s.v.mu.closed {
+		return ErrClosed
+	}
+	var err error
+	s.resolved, s.ok, err = s.v.resolveLocked(s.next)
+	return err
+}
+
+func (s *batchSet[T]) lock()   { s.v.mu.Lock() }
+func (s *batchSet[T]) unlock() { s.v.mu.Unlock() }
+
+func (s *batchSet[T]) store() {
+	if s.ok {
+		s.v.commitLocked(s.resolved)
+	}
+}
+
+// BeginBatch returns an empty Batch.
+func BeginBatch() *Batch {
+	return &Batch{index: make(map[UntypedVar]int)}
+}
+
+// BatchSet stages the next value of the variable within the Batch. If
+// the variable has already been staged, the value will be replaced.
+//
+// This should be a method whenever Go supports generic methods.
+func BatchSet[T any](b *Batch, v *Var[T], next T) {
+	entry := &batchSet[T]{next: next, v: v}
+	if idx, ok := b.index[v]; ok {
+		b.entries[idx] = entry
+		return
+	}
+	b.index[v] = len(b.entries)
+	b.entries = append(b.entries, entry)
+}
+
+// EndBatch stores the staged values and then resets the Batch. The
+// interceptors and validators of every variable are run before any
+// value is stored. If any variable is closed or any value is rejected,
+// no values will be stored and an error will be returned. A value that
+// an interceptor discards, by returning nil without proceeding, is
+// skipped without affecting the other values.
+func (b *Batch) EndBatch() error {
+	defer func() {
+		b.entries = nil
+		clear(b.index)
+	}()
+
+	batchMu.Lock()
+	defer batchMu.Unlock()
+	for _, entry := range b.entries {
+		entry.lock()
+		defer entry.unlock()
+	}
+
+	for idx, entry := range b.entries {
+		if err := entry.check(); err != nil {
+			return fmt.Errorf("batch entry %d: %w", idx, err)
+		}
+	}
+	for _, entry := range b.entries {
+		entry.store()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	r := require.New(t)
+
+	host := VarOf("localhost")
+	port := VarOf(80)
+	_, hostChanged := host.Get()
+	_, portChanged := port.Get()
+
+	b := BeginBatch()
+	BatchSet(b, host, "example.com")
+	BatchSet(b, port, 8080)
+	BatchSet(b, port, 443)
+
+	// Nothing is visible until the batch ends.
+	r.False(Changed(hostChanged))
+	r.Equal(80, port.Load())
+
+	r.NoError(b.EndBatch())
+	r.True(Changed(hostChanged))
+	r.True(Changed(portChanged))
+	r.Equal("example.com", host.Load())
+	r.Equal(443, port.Load())
+
+	// An ended batch is empty.
+	r.NoError(b.EndBatch())
+}
+
+func TestBatchRejected(t *testing.T) {
+	r := require.New(t)
+
+	boom := errors.New("boom")
+	host := VarOf("localhost")
+	port := VarOf(80, WithValidator(func(port int) error {
+		if port <= 0 {
+			return boom
+		}
+		return nil
+	}))
+
+	b := BeginBatch()
+	BatchSet(b, host, "example.com")
+	BatchSet(b, port, -1)
+	r.ErrorIs(b.EndBatch(), boom)
+	r.Equal("localhost", host.Load())
+	r.Equal(80, port.Load())
+
+	port.Close()
+	BatchSet(b, host, "example.com")
+	BatchSet(b, port, 443)
+	r.ErrorIs(b.EndBatch(), ErrClosed)
+	r.Equal("localhost", host.Load())
+}
+
+func TestBatchIntercepted(t *testing.T) {
+	r := require.New(t)
+
+	boom := errors.New("boom")
+	host := VarOf("localhost")
+	port := VarOf(80,
+		WithInterceptor(func(_, next int, proceed func(int) error) error {
+			if next == 0 {
+				return boom
+			}
+			// Substitute a value that the validator will reject.
+			return proceed(-next)
+		}),
+		WithValidator(func(port int) error {
+			if port <= 0 {
+				return errors.New("invalid port")
+			}
+			return nil
+		}))
+	_, hostChanged := host.Get()
+
+	b := BeginBatch()
+	BatchSet(b, host, "example.com")
+	BatchSet(b, port, 0)
+	r.ErrorIs(b.EndBatch(), boom)
+	r.Equal("localhost", host.Load())
+	r.Equal(80, port.Load())
+
+	BatchSet(b, host, "example.com")
+	BatchSet(b, port, 443)
+	r.ErrorContains(b.EndBatch(), "invalid port")
+	r.Equal("localhost", host.Load())
+	r.Equal(80, port.Load())
+	r.False(Changed(hostChanged))
+}
@@ -0,0 +1,66 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"reflect"
+)
+
+// Changed returns true if the notification channel, as returned from
+// [Var.Get] or similar methods, has been closed. That is, it reports
+// whether the value associated with the channel has been replaced. This
+// is a non-blocking check that is equivalent to:
+//
+//	select {
+//	case <-ch:
+//		return true
+//	default:
+//		return false
+//	}
+//
+// Callers that need to block until a change has occurred should receive
+// from the channel instead of polling this function.
+func Changed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Chain returns a channel that will be closed when any of the input
+// channels have been closed or when the context is canceled. If an input
+// channel has already been closed, no goroutine will be started.
+// Otherwise, a goroutine will wait until one of the above conditions
+// is met. Callers should ensure that the context will be canceled in
+// order to release the goroutine.
+func Chain(ctx context.Context, chs ...<-chan struct{}) <-chan struct{} {
+	ret := make(chan struct{})
+	for _, ch := range chs {
+		if Changed(ch) {
+			close(ret)
+			return ret
+		}
+	}
+
+	cases := make([]reflect.SelectCase, len(chs)+1)
+	cases[0] = reflect.SelectCase{
+		Chan: reflect.ValueOf(ctx.Done()),
+		Dir:  reflect.SelectRecv,
+	}
+	for i, ch := range chs {
+		cases[i+1] = reflect.SelectCase{
+			Chan: reflect.ValueOf(ch),
+			Dir:  reflect.SelectRecv,
+		}
+	}
+
+	go func() {
+		defer close(ret)
+		reflect.Select(cases)
+	}()
+	return ret
+}
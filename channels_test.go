@@ -0,0 +1,49 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	a := VarOf(1)
+	b := VarOf(2)
+	_, aCh := a.Get()
+	_, bCh := b.Get()
+
+	chained := Chain(ctx, aCh, bCh)
+	r.False(Changed(chained))
+
+	b.Set(3)
+	select {
+	case <-chained:
+	case <-ctx.Done():
+		r.NoError(ctx.Err())
+	}
+	r.False(Changed(aCh))
+	r.True(Changed(bCh))
+
+	// An already-closed channel should be reported immediately.
+	r.True(Changed(Chain(ctx, aCh, bCh)))
+
+	// Context cancellation should close the channel.
+	_, bCh = b.Get()
+	cancelCtx, cancelChain := context.WithCancel(ctx)
+	chained = Chain(cancelCtx, aCh, bCh)
+	cancelChain()
+	select {
+	case <-chained:
+	case <-ctx.Done():
+		r.NoError(ctx.Err())
+	}
+}
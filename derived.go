@@ -0,0 +1,381 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import "context"
+
+// A Pair is emitted by [Zip].
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Derived is a Var whose value is maintained by a goroutine that
+// tracks one or more source variables. The goroutine will exit when its
+// sources are closed, when the context that was used to construct the
+// Derived is canceled, or when [Derived.Close] is called. The Var will
+// be closed once the goroutine has exited.
+type Derived[T any] struct {
+	*Var[T]
+
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+	err    error // Set before done is closed.
+}
+
+// Close stops the goroutine that maintains the value of the Derived and
+// waits for it to exit. The Var will be closed once this method has
+// returned. This method must not be called from a callback that was
+// provided to the function that constructed the Derived.
+func (d *Derived[T]) Close() {
+	d.cancel(ErrClosed)
+	<-d.done
+}
+
+// Done returns a channel that is closed once the goroutine that
+// maintains the value of the Derived has exited.
+func (d *Derived[T]) Done() <-chan struct{} {
+	return d.done
+}
+
+// Err returns nil while the value of the Derived is being maintained.
+// Once the goroutine has exited, Err returns [ErrClosed] if the sources
+// or the Derived were closed, or the cause of the context's
+// cancellation.
+func (d *Derived[T]) Err() error {
+	select {
+	case <-d.done:
+		return d.err
+	default:
+		return nil
+	}
+}
+
+// Combine2 returns a Var that contains the result of applying the
+// function to the values of both sources. The returned Var is updated
+// whenever either source changes; a burst of changes will be coalesced
+// into a single update. The returned Var will be closed once all
+// sources have been closed or the context has been canceled.
+func Combine2[A, B, R any](ctx context.Context, a *Var[A], b *Var[B], fn func(A, B) R) *Derived[R] {
+	return combine(ctx, []UntypedVar{a, b}, func() R {
+		return fn(a.Load(), b.Load())
+	})
+}
+
+// Combine3 is analogous to [Combine2], for three sources.
+func Combine3[A, B, C, R any](
+	ctx context.Context, a *Var[A], b *Var[B], c *Var[C], fn func(A, B, C) R,
+) *Derived[R] {
+	return combine(ctx, []UntypedVar{a, b, c}, func() R {
+		return fn(a.Load(), b.Load(), c.Load())
+	})
+}
+
+// CombineAll returns a Var that contains the values of all sources. It
+// is otherwise analogous to [Combine2].
+func CombineAll[T any](ctx context.Context, sources ...*Var[T]) *Derived[[]T] {
+	vars := make([]UntypedVar, len(sources))
+	for i, source := range sources {
+		vars[i] = source
+	}
+	return combine(ctx, vars, func() []T {
+		ret := make([]T, len(sources))
+		for i, source := range sources {
+			ret[i] = source.Load()
+		}
+		return ret
+	})
+}
+
+// DistinctBy returns a Var that is updated with the value of the
+// source only when the key extracted from the value has changed. This
+// is useful when only part of a larger value is of interest, but the
+// entire value should be propagated. The returned Var will be closed
+// once the source has been closed or the context has been canceled.
+func DistinctBy[T any, K comparable](ctx context.Context, source *Var[T], key func(T) K) *Derived[T] {
+	data, version, changed := source.GetVersioned()
+	last := key(data)
+	return derive(ctx, data, func(ctx context.Context, out *Var[T]) {
+		follow(ctx, source, version, changed, func(data T) {
+			if next := key(data); next != last {
+				last = next
+				out.Set(data)
+			}
+		})
+	})
+}
+
+// Filter returns a Var that contains the most recent value of the
+// source which satisfies the predicate. If the initial value of the
+// source does not satisfy the predicate, the returned Var will contain
+// the zero value until an acceptable value is observed. The returned Var
+// will be closed once the source has been closed or the context has
+// been canceled.
+func Filter[T any](ctx context.Context, source *Var[T], pred func(T) bool) *Derived[T] {
+	data, version, changed := source.GetVersioned()
+	var initial T
+	if pred(data) {
+		initial = data
+	}
+	return derive(ctx, initial, func(ctx context.Context, out *Var[T]) {
+		follow(ctx, source, version, changed, func(data T) {
+			if pred(data) {
+				out.Set(data)
+			}
+		})
+	})
+}
+
+// Map returns a Var that contains the result of applying the function
+// to the value of the source. The returned Var is updated whenever the
+// source changes. This is useful when only part of a larger value is of
+// interest. The returned Var will be closed once the source has been
+// closed or the context has been canceled.
+func Map[A, B any](ctx context.Context, source *Var[A], fn func(A) B) *Derived[B] {
+	data, version, changed := source.GetVersioned()
+	return derive(ctx, fn(data), func(ctx context.Context, out *Var[B]) {
+		follow(ctx, source, version, changed, func(data A) {
+			out.Set(fn(data))
+		})
+	})
+}
+
+// Merge returns a channel that emits the changes made to any of the
+// sources, tagged with the index of the source. Multiple changes to a
+// single source that occur before they are received may be coalesced.
+// The channel will be closed once all sources have been closed or the
+// context has been canceled.
+func Merge[T any](ctx context.Context, sources ...*Var[T]) <-chan KeyedChange[int, T] {
+	agg := NewAggregation()
+	index := make(map[UntypedVar]int, len(sources))
+	versions := make([]uint64, len(sources)) // The last-emitted versions.
+	for i, source := range sources {
+		agg.Add(source)
+		index[source] = i
+		_, versions[i], _ = source.GetVersioned()
+	}
+
+	out := make(chan KeyedChange[int, T])
+	go func() {
+		defer close(out)
+		for agg.Len() > 0 {
+			waitCtx, cancel := context.WithCancel(ctx)
+			<-agg.Updated(waitCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			for found, ok := agg.Choose(); ok; found, ok = agg.Choose() {
+				// Re-arm before reading the value so that no change is
+				// missed. The version check discards the duplicate
+				// that may result, as well as the notification that
+				// occurs when a variable is closed.
+				agg.Add(found)
+				i := index[found]
+				data, version, _ := sources[i].GetVersioned()
+				if version == versions[i] {
+					continue
+				}
+				versions[i] = version
+				select {
+				case out <- KeyedChange[int, T]{Key: i, Value: data}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Scan returns a Var that contains a running aggregate of the values
+// of the source. The function is called with the seed and the initial
+// value of the source, and then with the previous aggregate and each
+// subsequent value. Changes to the source that occur in rapid
+// succession may be coalesced, so the function may not observe every
+// value that was stored. Closing the source does not invoke the
+// function. The returned Var will be closed once the source has been
+// closed or the context has been canceled.
+func Scan[A, B any](ctx context.Context, source *Var[A], seed B, fn func(acc B, next A) B) *Derived[B] {
+	data, version, changed := source.GetVersioned()
+	acc := fn(seed, data)
+	return derive(ctx, acc, func(ctx context.Context, out *Var[B]) {
+		follow(ctx, source, version, changed, func(data A) {
+			acc = fn(acc, data)
+			out.Set(acc)
+		})
+	})
+}
+
+// Switch returns a Var that contains the value of the inner variable
+// that is currently selected by the outer variable. When the outer
+// variable changes, the returned Var is updated with the value of the
+// newly-selected inner variable, which will then be tracked. If the
+// outer variable contains nil, the returned Var retains its value. The
+// returned Var will be closed once the outer variable and the selected
+// inner variable have been closed or the context has been canceled.
+func Switch[T any](ctx context.Context, outer *Var[*Var[T]]) *Derived[T] {
+	// get returns a nil channel once the variable has been closed, since
+	// it will never change again.
+	get := func(v *Var[T]) (T, <-chan struct{}) {
+		entry, changed, closed := v.state()
+		if closed {
+			changed = nil
+		}
+		return entry.Value, changed
+	}
+	getOuter := func() (*Var[T], <-chan struct{}) {
+		entry, changed, closed := outer.state()
+		if closed {
+			changed = nil
+		}
+		return entry.Value, changed
+	}
+
+	var data T
+	var innerChanged <-chan struct{}
+	inner, outerChanged := getOuter()
+	if inner != nil {
+		data, innerChanged = get(inner)
+	}
+	return derive(ctx, data, func(ctx context.Context, out *Var[T]) {
+		for outerChanged != nil || innerChanged != nil {
+			select {
+			case <-outerChanged:
+				var next *Var[T]
+				next, outerChanged = getOuter()
+				if next == inner {
+					continue
+				}
+				inner, innerChanged = next, nil
+				if inner == nil {
+					continue
+				}
+			case <-innerChanged:
+			case <-ctx.Done():
+				return
+			}
+			data, innerChanged = get(inner)
+			out.Set(data)
+		}
+	})
+}
+
+// Zip returns a Var that is updated with a Pair of values only once
+// both sources have changed since the previous update. This allows the
+// values of the sources to be consumed in lock-step. If a source
+// changes more than once before the other source changes, only its
+// latest value will be paired. The returned Var initially contains the
+// current values of both sources. It will be closed once no further
+// pairs can be produced, because a source has been closed, or the
+// context has been canceled.
+func Zip[A, B any](ctx context.Context, a *Var[A], b *Var[B]) *Derived[Pair[A, B]] {
+	aData, aVersion, aChanged := a.GetVersioned()
+	bData, bVersion, bChanged := b.GetVersioned()
+	return derive(ctx, Pair[A, B]{aData, bData}, func(ctx context.Context, out *Var[Pair[A, B]]) {
+		aLast, bLast := aVersion, bVersion
+		for {
+			select {
+			case <-aChanged:
+			case <-bChanged:
+			case <-ctx.Done():
+				return
+			}
+			aEntry, aNext, aClosed := a.state()
+			bEntry, bNext, bClosed := b.state()
+			aData, aVersion, aChanged = aEntry.Value, aEntry.Version, aNext
+			bData, bVersion, bChanged = bEntry.Value, bEntry.Version, bNext
+			if aVersion != aLast && bVersion != bLast {
+				out.Set(Pair[A, B]{aData, bData})
+				aLast, bLast = aVersion, bVersion
+			}
+			// A closed source with no unpaired value can never produce
+			// another pair.
+			if (aClosed && aVersion == aLast) || (bClosed && bVersion == bLast) {
+				return
+			}
+			// Avoid spinning on the channel of a closed source.
+			if aClosed {
+				aChanged = nil
+			}
+			if bClosed {
+				bChanged = nil
+			}
+		}
+	})
+}
+
+// combine implements the Combine functions. The compute function is
+// called to produce a new value after any of the variables have
+// changed.
+func combine[R any](ctx context.Context, vars []UntypedVar, compute func() R) *Derived[R] {
+	// A private Aggregation provides a single wake channel for all of
+	// the variables. The variables must be registered before compute is
+	// called so that no change will be missed. Closed variables are not
+	// registered, so the loop exits once every variable is closed.
+	agg := NewAggregation()
+	agg.Add(vars...)
+	return derive(ctx, compute(), func(ctx context.Context, out *Var[R]) {
+		for agg.Len() > 0 {
+			<-agg.Updated(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			agg.Add(agg.Drain()...)
+			out.Set(compute())
+		}
+	})
+}
+
+// derive starts a goroutine to execute the function, which should
+// return once the context has been canceled.
+func derive[T any](
+	ctx context.Context, initial T, fn func(ctx context.Context, out *Var[T]),
+) *Derived[T] {
+	ctx, cancel := context.WithCancelCause(ctx)
+	d := &Derived[T]{
+		Var:    VarOf(initial),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(d.done)
+		defer d.Var.Close()
+		fn(ctx, d.Var)
+		if ctx.Err() != nil {
+			d.err = context.Cause(ctx)
+		} else {
+			d.err = ErrClosed
+		}
+		cancel(nil)
+	}()
+	return d
+}
+
+// follow calls the function with the value of the source each time the
+// source's version advances beyond the given version. Closing the
+// source does not advance its version, so the function will not be
+// called again for an unchanged final value. It returns once the source
+// has been closed or the context has been canceled.
+func follow[T any](
+	ctx context.Context, source *Var[T], version uint64, changed <-chan struct{}, fn func(T),
+) {
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+		var next Entry[T]
+		var closed bool
+		next, changed, closed = source.state()
+		if next.Version != version {
+			version = next.Version
+			fn(next.Value)
+		}
+		if closed {
+			return
+		}
+	}
+}
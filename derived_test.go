@@ -0,0 +1,376 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	source := VarOf(1)
+	out := Map(ctx, source, strconv.Itoa)
+	r.Equal("1", out.Load())
+
+	source.Set(2)
+	_, err := out.Wait(ctx, func(s string) bool { return s == "2" })
+	r.NoError(err)
+
+	source.Set(3)
+	source.Close()
+	_, err = out.Wait(ctx, func(string) bool { return out.Closed() })
+	r.NoError(err)
+	r.Equal("3", out.Load())
+}
+
+func TestMapClosedUnchanged(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	source := VarOf(1)
+	out := Map(ctx, source, strconv.Itoa)
+	_, version, _ := out.GetVersioned()
+
+	// Closing the source does not store a value in the output.
+	source.Close()
+	<-out.Done()
+	_, found, _ := out.GetVersioned()
+	r.Equal(version, found)
+	r.Equal("1", out.Load())
+}
+
+func TestMapCanceled(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	out := Map(ctx, VarOf(1), strconv.Itoa)
+	cancel()
+	<-out.changed()
+	r.True(out.Closed())
+}
+
+func TestCombine(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	a := VarOf(1)
+	b := VarOf("b")
+	c := VarOf(true)
+	out2 := Combine2(ctx, a, b, func(a int, b string) string {
+		return strconv.Itoa(a) + b
+	})
+	out3 := Combine3(ctx, a, b, c, func(a int, b string, c bool) string {
+		return strconv.Itoa(a) + b + strconv.FormatBool(c)
+	})
+	r.Equal("1b", out2.Load())
+	r.Equal("1btrue", out3.Load())
+
+	a.Set(2)
+	c.Set(false)
+	_, err := out3.Wait(ctx, func(s string) bool { return s == "2bfalse" })
+	r.NoError(err)
+	_, err = out2.Wait(ctx, func(s string) bool { return s == "2b" })
+	r.NoError(err)
+
+	// The outputs are closed once all sources are closed.
+	a.Close()
+	b.Set("x")
+	b.Close()
+	_, err = out2.Wait(ctx, func(string) bool { return out2.Closed() })
+	r.NoError(err)
+	r.Equal("2x", out2.Load())
+	r.False(out3.Closed())
+	c.Close()
+	_, err = out3.Wait(ctx, func(string) bool { return out3.Closed() })
+	r.NoError(err)
+}
+
+func TestCombineAll(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	sources := []*Var[int]{VarOf(0), VarOf(0), VarOf(0)}
+	out := CombineAll(ctx, sources...)
+	r.Equal([]int{0, 0, 0}, out.Load())
+
+	// A burst of changes is coalesced.
+	for i, source := range sources {
+		source.Set(i + 1)
+	}
+	_, err := out.Wait(ctx, func(values []int) bool {
+		return slices.Equal(values, []int{1, 2, 3})
+	})
+	r.NoError(err)
+	_, version, _ := out.GetVersioned()
+	r.LessOrEqual(version, uint64(3))
+
+	cancel()
+	_, err = out.Wait(context.Background(), func([]int) bool { return out.Closed() })
+	r.NoError(err)
+}
+
+func TestCombineBurst(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	sources := []*Var[int]{VarOf(0), VarOf(0), VarOf(0)}
+	var calls atomic.Int32
+	entered := make(chan struct{})
+	gate := make(chan struct{})
+	out := combine(ctx, []UntypedVar{sources[0], sources[1], sources[2]}, func() int {
+		if calls.Add(1) == 2 {
+			close(entered)
+			<-gate
+		}
+		sum := 0
+		for _, source := range sources {
+			sum += source.Load()
+		}
+		return sum
+	})
+
+	// Hold the first recomputation while a burst of changes is made to
+	// every source.
+	sources[0].Set(1)
+	<-entered
+	for i := range 10 {
+		for _, source := range sources {
+			source.Set(i)
+		}
+	}
+	close(gate)
+
+	// The burst results in a single recomputation, which is followed
+	// by one for the final change.
+	r.Eventually(func() bool { return calls.Load() == 3 }, time.Minute, time.Millisecond)
+	sources[0].Set(100)
+	_, err := out.Wait(ctx, func(sum int) bool { return sum == 118 })
+	r.NoError(err)
+	r.Equal(int32(4), calls.Load())
+}
+
+func TestFilter(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	even := func(i int) bool { return i%2 == 0 }
+	r.Equal(0, Filter(ctx, VarOf(3), even).Load())
+
+	source := VarOf(2)
+	out := Filter(ctx, source, even)
+	r.Equal(2, out.Load())
+
+	source.Set(3)
+	source.Set(4)
+	_, err := out.Wait(ctx, func(i int) bool { return i == 4 })
+	r.NoError(err)
+
+	// Rejected values are not propagated.
+	source.Set(5)
+	source.Close()
+	_, err = out.Wait(ctx, func(int) bool { return out.Closed() })
+	r.NoError(err)
+	r.Equal(4, out.Load())
+}
+
+func TestScan(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	source := VarOf(3)
+	out := Scan(ctx, source, 0, func(acc, next int) int { return max(acc, next) })
+	r.Equal(3, out.Load())
+
+	// Track a high-water mark.
+	source.Set(5)
+	_, err := out.Wait(ctx, func(i int) bool { return i == 5 })
+	r.NoError(err)
+	source.Set(1)
+	source.Close()
+	_, err = out.Wait(ctx, func(int) bool { return out.Closed() })
+	r.NoError(err)
+	r.Equal(5, out.Load())
+}
+
+func TestScanSum(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	source := VarOf(1)
+	out := Scan(ctx, source, 0, func(acc, next int) int { return acc + next })
+	r.Equal(1, out.Load())
+
+	source.Set(2)
+	_, err := out.Wait(ctx, func(i int) bool { return i == 3 })
+	r.NoError(err)
+
+	// Closing the source must not count the final value again.
+	source.Close()
+	<-out.Done()
+	r.Equal(3, out.Load())
+}
+
+func TestDerived(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := VarOf(1)
+	closed := Map(ctx, source, strconv.Itoa)
+	canceled := Map(ctx, source, strconv.Itoa)
+	finished := Map(context.Background(), source, strconv.Itoa)
+	r.NoError(closed.Err())
+
+	// Closing the handle waits for the goroutine to exit.
+	closed.Close()
+	r.True(closed.Closed())
+	r.ErrorIs(closed.Err(), ErrClosed)
+	source.Set(2)
+	r.Equal("1", closed.Load())
+
+	cancel()
+	<-canceled.Done()
+	r.True(canceled.Closed())
+	r.ErrorIs(canceled.Err(), context.Canceled)
+
+	source.Close()
+	<-finished.Done()
+	r.Equal("2", finished.Load())
+	r.ErrorIs(finished.Err(), ErrClosed)
+}
+
+func TestDistinctBy(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	type config struct {
+		Name  string
+		Count int
+	}
+	source := VarOf(config{"a", 1})
+	out := DistinctBy(ctx, source, func(c config) string { return c.Name })
+	_, version, _ := out.GetVersioned()
+
+	// A change to another field is not propagated.
+	source.Set(config{"a", 2})
+	source.Set(config{"b", 3})
+	_, err := out.Wait(ctx, func(c config) bool { return c.Name == "b" })
+	r.NoError(err)
+	r.Equal(config{"b", 3}, out.Load())
+	_, next, _ := out.GetVersioned()
+	r.Equal(version+1, next)
+}
+
+func TestSwitch(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	a := VarOf("a")
+	b := VarOf("b")
+	outer := VarOf(a)
+	out := Switch(ctx, outer)
+	r.Equal("a", out.Load())
+
+	a.Set("a1")
+	_, err := out.Wait(ctx, func(s string) bool { return s == "a1" })
+	r.NoError(err)
+
+	// Changes to the previous selection are ignored.
+	outer.Set(b)
+	_, err = out.Wait(ctx, func(s string) bool { return s == "b" })
+	r.NoError(err)
+	a.Set("a2")
+	b.Set("b1")
+	_, err = out.Wait(ctx, func(s string) bool { return s == "b1" })
+	r.NoError(err)
+
+	// A nil selection retains the value.
+	outer.Set(nil)
+	outer.Set(a)
+	_, err = out.Wait(ctx, func(s string) bool { return s == "a2" })
+	r.NoError(err)
+
+	// The inner variable is tracked after the outer is closed.
+	outer.Close()
+	a.Set("a3")
+	_, err = out.Wait(ctx, func(s string) bool { return s == "a3" })
+	r.NoError(err)
+	r.False(out.Closed())
+	a.Close()
+	_, err = out.Wait(ctx, func(string) bool { return out.Closed() })
+	r.NoError(err)
+}
+
+func TestMerge(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	a := VarOf("a")
+	b := VarOf("b")
+	changes := Merge(ctx, a, b)
+
+	b.Set("b1")
+	r.Equal(KeyedChange[int, string]{1, "b1"}, <-changes)
+	a.Set("a1")
+	r.Equal(KeyedChange[int, string]{0, "a1"}, <-changes)
+
+	// The channel is closed once all sources are closed. Closure alone
+	// is not reported as a change.
+	a.Set("a2")
+	a.Close()
+	b.Close()
+	r.Equal(KeyedChange[int, string]{0, "a2"}, <-changes)
+	_, open := <-changes
+	r.False(open)
+}
+
+func TestZip(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	req := VarOf(0)
+	resp := VarOf("")
+	out := Zip(ctx, req, resp)
+	r.Equal(Pair[int, string]{0, ""}, out.Load())
+	_, version, _ := out.GetVersioned()
+
+	// A change to only one source does not produce a pair.
+	req.Set(1)
+	req.Set(2)
+	resp.Set("two")
+	_, err := out.Wait(ctx, func(p Pair[int, string]) bool { return p.Second == "two" })
+	r.NoError(err)
+	r.Equal(Pair[int, string]{2, "two"}, out.Load())
+	_, next, _ := out.GetVersioned()
+	r.Equal(version+1, next)
+
+	// An unpaired value is paired after its source is closed.
+	req.Set(3)
+	req.Close()
+	resp.Set("three")
+	_, err = out.Wait(ctx, func(Pair[int, string]) bool { return out.Closed() })
+	r.NoError(err)
+	r.Equal(Pair[int, string]{3, "three"}, out.Load())
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// A Change is emitted by [Var.WatchEvents].
+type Change[T any] struct {
+	Old     T         // The value of the previously-emitted Change.
+	New     T         // The value of the Var.
+	Version uint64    // See [Var.GetVersioned].
+	Time    time.Time // The time at which New was stored.
+}
+
+// WatchEvents returns a channel that emits a Change after each change
+// to the Var. Changes that occur while the receiver is falling behind
+// will be conflated, so the Old value of a Change is always the New
+// value of the previous Change, or the value of the Var when this
+// method was called. The channel will be closed when the context is
+// canceled or after the final change to a closed Var has been received.
+func (v *Var[T]) WatchEvents(ctx context.Context) <-chan Change[T] {
+	out := make(chan Change[T])
+	last, changed, _ := v.state()
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+
+			var next Entry[T]
+			var closed bool
+			next, changed, closed = v.state()
+			if next.Version == last.Version {
+				// The Var was closed without a change.
+				return
+			}
+			replaced := changed
+			if closed {
+				replaced = nil
+			}
+
+			select {
+			case out <- Change[T]{
+				Old:     last.Value,
+				New:     next.Value,
+				Version: next.Version,
+				Time:    next.Stored,
+			}:
+				last = next
+				if closed {
+					return
+				}
+			case <-replaced:
+				// Emit a Change with the latest value instead. Since
+				// changed has been closed, the next loop will not wait.
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchEvents(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	events := v.WatchEvents(ctx)
+
+	v.Set(1)
+	change := <-events
+	r.Equal(0, change.Old)
+	r.Equal(1, change.New)
+	r.Equal(uint64(1), change.Version)
+	r.False(change.Time.IsZero())
+
+	// Conflated changes report the previously-emitted value.
+	for i := 2; i <= 10; i++ {
+		v.Set(i)
+	}
+	old := 1
+	for change := range events {
+		r.Equal(old, change.Old)
+		old = change.New
+		if change.New == 10 {
+			break
+		}
+	}
+
+	// The final change is delivered before the channel is closed.
+	v.Set(11)
+	v.Close()
+	var last Change[int]
+	for change := range events {
+		last = change
+	}
+	r.Equal(Change[int]{10, 11, 11, last.Time}, last)
+}
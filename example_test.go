@@ -63,12 +63,9 @@ func ExampleAggregation() {
 		if !ok {
 			continue
 		}
-		switch t := found.(type) {
-		case *notify.Var[int]:
-			val, _ := t.Get()
+		if val, ok := notify.ValueOf[int](found); ok {
 			seen = append(seen, strconv.Itoa(val))
-		case *notify.Var[string]:
-			val, _ := t.Get()
+		} else if val, ok := notify.ValueOf[string](found); ok {
 			seen = append(seen, val)
 		}
 	}
@@ -110,3 +107,19 @@ func ExampleVar() {
 		}
 	}
 }
+
+// This example shows how Swap can be used to replace a value and
+// dispose of the previous one without racing against other writers.
+func ExampleVar_Swap() {
+	v := notify.VarOf("first")
+
+	prev, _ := v.Swap("second")
+	fmt.Println(prev)
+
+	prev, _ = v.Swap("third")
+	fmt.Println(prev)
+
+	// Output:
+	// first
+	// second
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import "time"
+
+// An Entry records a value that was stored in a Var. See
+// [Var.EnableHistory].
+type Entry[T any] struct {
+	Stored  time.Time // The time at which the value was stored.
+	Value   T
+	Version uint64 // See [Var.GetVersioned].
+}
+
+// EnableHistory causes the Var to retain the last n values that were
+// stored in it, including the current value. Calling this method again
+// will resize the history, discarding the oldest entries if necessary.
+// A non-positive value of n disables the history.
+func (v *Var[T]) EnableHistory(n int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if n <= 0 {
+		v.mu.history = historyRing[T]{}
+		return
+	}
+	enabled := v.mu.history.enabled()
+	v.mu.history.resize(n)
+	if !enabled {
+		v.recordLocked()
+	}
+}
+
+// History returns the values retained by the Var, ordered from oldest
+// to newest. This method will return nil unless [Var.EnableHistory] has
+// been called.
+func (v *Var[T]) History() []Entry[T] {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.mu.history.entries()
+}
+
+// recordLocked appends the current value to the history, if enabled.
+func (v *Var[T]) recordLocked() {
+	if !v.mu.history.enabled() {
+		return
+	}
+	v.mu.history.push(v.entryLocked())
+}
+
+// historyRing is a fixed-capacity ring buffer of entries, so that
+// recording a value does not require the existing entries to be moved.
+type historyRing[T any] struct {
+	buf  []Entry[T] // The length is the capacity of the ring.
+	head int        // The index of the oldest entry.
+	size int        // The number of entries in use.
+}
+
+func (h *historyRing[T]) enabled() bool {
+	return len(h.buf) > 0
+}
+
+// entries returns a copy of the entries, ordered from oldest to newest.
+func (h *historyRing[T]) entries() []Entry[T] {
+	if h.size == 0 {
+		return nil
+	}
+	ret := make([]Entry[T], h.size)
+	for i := range ret {
+		ret[i] = h.buf[(h.head+i)%len(h.buf)]
+	}
+	return ret
+}
+
+// push appends the entry, overwriting the oldest entry if the ring is
+// full.
+func (h *historyRing[T]) push(entry Entry[T]) {
+	if h.size < len(h.buf) {
+		h.buf[(h.head+h.size)%len(h.buf)] = entry
+		h.size++
+		return
+	}
+	h.buf[h.head] = entry
+	h.head = (h.head + 1) % len(h.buf)
+}
+
+// resize changes the capacity of the ring, retaining the newest
+// entries.
+func (h *historyRing[T]) resize(n int) {
+	entries := h.entries()
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	h.buf = make([]Entry[T], n)
+	h.head = 0
+	h.size = copy(h.buf, entries)
+}
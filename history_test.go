@@ -0,0 +1,55 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(0)
+	r.Nil(v.History())
+
+	v.EnableHistory(3)
+	for i := 1; i <= 5; i++ {
+		v.Set(i)
+	}
+
+	values := func(entries []Entry[int]) []int {
+		var ret []int
+		for _, entry := range entries {
+			r.False(entry.Stored.IsZero())
+			r.Equal(uint64(entry.Value), entry.Version)
+			ret = append(ret, entry.Value)
+		}
+		return ret
+	}
+	r.Equal([]int{3, 4, 5}, values(v.History()))
+	r.Equal([]int{3, 4, 5}, values(v.Reader().History()))
+
+	// Shrinking the history discards the oldest entries.
+	v.EnableHistory(1)
+	r.Equal([]int{5}, values(v.History()))
+
+	// Growing the history retains the existing entries, and the oldest
+	// entries are overwritten once it is full.
+	v.EnableHistory(3)
+	for i := 6; i <= 10; i++ {
+		v.Set(i)
+		r.Equal(i, v.History()[len(v.History())-1].Value)
+	}
+	r.Equal([]int{8, 9, 10}, values(v.History()))
+
+	// Rejected or no-op writes are not recorded.
+	v.Close()
+	v.Set(11)
+	r.Equal([]int{8, 9, 10}, values(v.History()))
+
+	v.EnableHistory(0)
+	r.Nil(v.History())
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// A Hub fans out the values of a Var to many subscribers, which may be
+// added or removed at any time. Each subscriber has its own buffer; by
+// default, a slow subscriber will lose its oldest buffered values
+// rather than blocking writers to the Var. Subscribers that require a
+// different policy may be added with [Hub.Subscribe].
+type Hub[T any] struct {
+	buffer int
+	v      *Var[T]
+
+	mu struct {
+		sync.Mutex
+		closed bool
+		subs   map[*Subscription[T]]struct{}
+	}
+}
+
+// NewHub constructs a Hub for the Var. The buffer size applies to each
+// subscriber; see [SubscribeOptions.Buffer].
+func NewHub[T any](v *Var[T], buffer int) *Hub[T] {
+	h := &Hub[T]{buffer: buffer, v: v}
+	h.mu.subs = make(map[*Subscription[T]]struct{})
+	return h
+}
+
+// Add returns a new Subscription to the Var, using the Hub's buffer
+// size and the [OverflowDropOldest] policy. The subscription may be
+// removed by canceling the context or by calling [Subscription.Close].
+// If the Hub has been closed, the returned subscription will have
+// ended.
+func (h *Hub[T]) Add(ctx context.Context) *Subscription[T] {
+	return h.Subscribe(ctx, SubscribeOptions{
+		Buffer:   h.buffer,
+		Overflow: OverflowDropOldest,
+	})
+}
+
+// Close ends all subscriptions and prevents new ones from being added.
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.mu.closed = true
+	for sub := range h.mu.subs {
+		sub.Close()
+	}
+	clear(h.mu.subs)
+}
+
+// Len returns the number of active subscriptions.
+func (h *Hub[T]) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pruneLocked()
+	return len(h.mu.subs)
+}
+
+// Subscribe is analogous to [Hub.Add], but allows each subscriber to
+// choose its own options. For example, one subscriber may use
+// [OverflowConflate] while another uses [OverflowBlock] to receive
+// every value.
+func (h *Hub[T]) Subscribe(ctx context.Context, opts SubscribeOptions) *Subscription[T] {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sub := h.v.Subscribe(ctx, opts)
+	if h.mu.closed {
+		sub.Close()
+		return sub
+	}
+	h.pruneLocked()
+	h.mu.subs[sub] = struct{}{}
+	return sub
+}
+
+// pruneLocked discards subscriptions which have ended.
+func (h *Hub[T]) pruneLocked() {
+	for sub := range h.mu.subs {
+		if sub.Err() != nil {
+			delete(h.mu.subs, sub)
+		}
+	}
+}
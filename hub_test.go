@@ -0,0 +1,78 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	hub := NewHub(v, 1)
+	fast := hub.Add(ctx)
+	slow := hub.Add(ctx)
+	r.Equal(2, hub.Len())
+
+	// A slow subscriber does not block the writer.
+	r.Equal(0, <-fast.C)
+	for i := 1; i <= 10; i++ {
+		v.Set(i)
+		r.Equal(i, <-fast.C)
+	}
+	r.Equal(9, <-slow.C)
+	r.Equal(10, <-slow.C)
+
+	slow.Close()
+	r.Equal(1, hub.Len())
+
+	subCtx, subCancel := context.WithCancel(ctx)
+	canceled := hub.Add(subCtx)
+	subCancel()
+	<-canceled.Done()
+	r.Equal(1, hub.Len())
+
+	hub.Close()
+	r.Equal(0, hub.Len())
+	r.ErrorIs(fast.Err(), ErrClosed)
+	r.ErrorIs(hub.Add(ctx).Err(), ErrClosed)
+}
+
+func TestHubPolicies(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	hub := NewHub(v, 0)
+	dashboard := hub.Subscribe(ctx, SubscribeOptions{Buffer: 10, Overflow: OverflowConflate})
+	replica := hub.Subscribe(ctx, SubscribeOptions{Overflow: OverflowBlock})
+
+	var replicated []int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for value := range replica.C {
+			replicated = append(replicated, value)
+		}
+	}()
+
+	for i := 1; i <= 10; i++ {
+		v.Set(i)
+	}
+	hub.Close()
+	<-done
+
+	// The conflating subscriber sees only the latest value, while the
+	// blocking subscriber sees every value.
+	r.Equal(10, <-dashboard.C)
+	r.Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, replicated)
+}
@@ -0,0 +1,22 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package varstate allows the other packages in this module to read the
+// state of a notify.Var under a single acquisition of its lock, without
+// adding such a method to the public API.
+package varstate
+
+// Get is installed by the notify package. It returns the current value
+// and version of a *notify.Var, its notification channel, and whether
+// it has been closed.
+var Get func(v any) (value any, version uint64, changed <-chan struct{}, closed bool)
+
+// Load is a typed wrapper around Get. A caller that observes the Var to
+// be closed has also observed its final value.
+func Load[T any](v any) (value T, version uint64, changed <-chan struct{}, closed bool) {
+	boxed, version, changed, closed := Get(v)
+	// The type assertion fails for a nil interface value, in which case
+	// the zero value is correct.
+	value, _ = boxed.(T)
+	return value, version, changed, closed
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A KeyedChange is emitted by [KeyedVars.Changes].
+type KeyedChange[K comparable, T any] struct {
+	Key   K // The key of the variable that changed.
+	Value T // The value of the variable when the change was observed.
+}
+
+// KeyedVars lazily creates a Var for each key. This is useful for
+// per-tenant or per-connection settings, where the set of keys is not
+// known ahead of time. Variables that have been neither retrieved nor
+// written for the configured time-to-live will be closed and discarded.
+// A caller that retains a variable beyond its time-to-live can detect
+// that it has expired, since [Var.TrySet] and [Var.Update] will return
+// [ErrClosed]; retrieving the key again will create a new variable.
+type KeyedVars[K comparable, T any] struct {
+	initial func(K) T
+	keys    Var[struct{}] // Notified when a key is added.
+	opts    []Option[T]
+	ttl     time.Duration
+
+	mu struct {
+		sync.Mutex
+		m map[K]*keyedEntry[T]
+	}
+}
+
+type keyedEntry[T any] struct {
+	touched time.Time // The time of the last retrieval or write.
+	v       *Var[T]
+	version uint64 // The version of v when last checked by expire.
+}
+
+// NewKeyedVars constructs a KeyedVars. The initial function provides
+// the starting value for a newly-created variable and the options will
+// be applied to each variable. If the time-to-live is positive, a
+// goroutine will periodically discard expired variables until the
+// context is canceled.
+func NewKeyedVars[K comparable, T any](
+	ctx context.Context, ttl time.Duration, initial func(K) T, opts ...Option[T],
+) *KeyedVars[K, T] {
+	k := &KeyedVars[K, T]{
+		initial: initial,
+		opts:    opts,
+		ttl:     ttl,
+	}
+	k.mu.m = make(map[K]*keyedEntry[T])
+	if ttl > 0 {
+		go func() {
+			// Avoid a zero interval when ttl is a single nanosecond.
+			ticker := time.NewTicker(max(ttl/2, 1))
+			defer ticker.Stop()
+			for {
+				select {
+				case now := <-ticker.C:
+					k.expire(now)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	return k
+}
+
+// Changes returns a channel that emits the key and value of any
+// variable that changes. A variable that is created after the call to
+// Changes will be reported with its initial value. Multiple changes to a
+// single variable that occur before they are received may be coalesced.
+// The channel will be closed when the context is canceled.
+func (k *KeyedVars[K, T]) Changes(ctx context.Context) <-chan KeyedChange[K, T] {
+	agg := NewAggregation()
+	known := make(map[UntypedVar]K)
+
+	// discover aggregates any new variables, returning those that were
+	// created after the initial call.
+	discover := func() (<-chan struct{}, []KeyedChange[K, T]) {
+		_, added := k.keys.Get()
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		var created []KeyedChange[K, T]
+		for key, entry := range k.mu.m {
+			if _, ok := known[entry.v]; !ok {
+				created = append(created, KeyedChange[K, T]{key, Aggregate(agg, entry.v)})
+				known[entry.v] = key
+			}
+		}
+		return added, created
+	}
+	added, _ := discover()
+
+	out := make(chan KeyedChange[K, T])
+	go func() {
+		defer close(out)
+		var pending []KeyedChange[K, T]
+		for {
+			for _, change := range pending {
+				select {
+				case out <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+			pending = pending[:0]
+
+			waitCtx, cancel := context.WithCancel(ctx)
+			select {
+			case <-agg.Updated(waitCtx):
+			case <-added:
+			}
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+
+			added, pending = discover()
+			for found, ok := agg.Choose(); ok; found, ok = agg.Choose() {
+				key := known[found]
+				if found.Closed() {
+					// The variable has expired or was deleted.
+					delete(known, found)
+					continue
+				}
+				v := found.(*Var[T])
+				pending = append(pending, KeyedChange[K, T]{key, Aggregate(agg, v)})
+			}
+		}
+	}()
+	return out
+}
+
+// Delete closes and discards the variable for the key, if it exists.
+func (k *KeyedVars[K, T]) Delete(key K) {
+	k.mu.Lock()
+	entry, ok := k.mu.m[key]
+	delete(k.mu.m, key)
+	k.mu.Unlock()
+	if ok {
+		entry.v.Close()
+	}
+}
+
+// Get returns the variable for the key, creating it if necessary.
+// Retrieving or writing to a variable resets its time-to-live.
+func (k *KeyedVars[K, T]) Get(key K) *Var[T] {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := time.Now()
+	if entry, ok := k.mu.m[key]; ok {
+		entry.touched = now
+		return entry.v
+	}
+	v := VarOf(k.initial(key), k.opts...)
+	k.mu.m[key] = &keyedEntry[T]{touched: now, v: v}
+	k.keys.Notify()
+	return v
+}
+
+// Len returns the number of variables.
+func (k *KeyedVars[K, T]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.mu.m)
+}
+
+// expire closes and discards the variables which have been neither
+// retrieved nor written since the time-to-live elapsed.
+func (k *KeyedVars[K, T]) expire(now time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for key, entry := range k.mu.m {
+		if current, _, _ := entry.v.state(); current.Version != entry.version {
+			entry.version = current.Version
+			if current.Stored.After(entry.touched) {
+				entry.touched = current.Stored
+			}
+		}
+		if now.Sub(entry.touched) >= k.ttl {
+			delete(k.mu.m, key)
+			entry.v.Close()
+		}
+	}
+}
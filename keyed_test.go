@@ -0,0 +1,88 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedVars(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	k := NewKeyedVars(ctx, 0, strings.ToUpper)
+	a := k.Get("a")
+	r.Equal("A", a.Load())
+	r.Same(a, k.Get("a"))
+	r.Equal(1, k.Len())
+
+	changes := k.Changes(ctx)
+
+	// Changes to variables created before or after the call to Changes
+	// are both reported.
+	a.Set("a1")
+	r.Equal(KeyedChange[string, string]{"a", "a1"}, <-changes)
+	k.Get("b").Set("b1")
+	r.Equal(KeyedChange[string, string]{"b", "b1"}, <-changes)
+
+	k.Delete("a")
+	r.True(a.Closed())
+	r.Equal(1, k.Len())
+	r.NotSame(a, k.Get("a"))
+
+	cancel()
+	for range changes {
+	}
+}
+
+func TestKeyedVarsTTL(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	k := NewKeyedVars(ctx, time.Hour, func(string) int { return 0 })
+	a := k.Get("a")
+	b := k.Get("b")
+
+	// Touch b after a would have expired.
+	k.mu.m["a"].touched = time.Now().Add(-2 * time.Hour)
+	k.expire(time.Now())
+	r.True(a.Closed())
+	r.False(b.Closed())
+	r.Equal(1, k.Len())
+
+	// Writing to a variable also resets its time-to-live.
+	k.mu.m["b"].touched = time.Now().Add(-2 * time.Hour)
+	b.Set(1)
+	k.expire(time.Now())
+	r.False(b.Closed())
+	r.Same(b, k.Get("b"))
+
+	// Writes to an expired variable are detectable.
+	k.mu.m["b"].touched = time.Now().Add(-2 * time.Hour)
+	k.expire(time.Now().Add(2 * time.Hour))
+	r.True(b.Closed())
+	_, err := b.TrySet(2)
+	r.ErrorIs(err, ErrClosed)
+	r.NotSame(b, k.Get("b"))
+
+	// A short time-to-live is enforced in the background.
+	k = NewKeyedVars(ctx, time.Millisecond, func(string) int { return 0 })
+	a = k.Get("a")
+	<-a.changed()
+	r.True(a.Closed())
+	r.Equal(0, k.Len())
+
+	// The smallest time-to-live must not panic.
+	k = NewKeyedVars(ctx, time.Nanosecond, func(string) int { return 0 })
+	a = k.Get("a")
+	<-a.changed()
+	r.True(a.Closed())
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// The functions in this file mirror those in the notifyx package, but
+// accept a plain [context.Context] for use in code that does not
+// otherwise depend upon the stopper package.
+
+// DoWhenChanged executes the callback when the variable has changed to
+// a different value. That is, if the variable is set to existing value,
+// the callback will not be invoked. If an error is returned from the
+// callback, the last successfully-processed value will be returned.
+// This function returns once the source has been closed or the context
+// has been canceled.
+func DoWhenChanged[T comparable](
+	ctx context.Context,
+	start T,
+	source *Var[T],
+	fn func(ctx context.Context, old, new T) error,
+) (last T, err error) {
+	return DoWhenChangedFunc(ctx, start, source, func(a, b T) bool { return a == b }, fn)
+}
+
+// DoWhenChangedFunc executes the callback when the variable has changed
+// to a value which is not equal to the previous value, as determined by
+// the comparator. This function returns once the source has been closed
+// or the context has been canceled.
+func DoWhenChangedFunc[T any](
+	ctx context.Context,
+	start T,
+	source *Var[T],
+	eq func(a, b T) bool,
+	fn func(ctx context.Context, old, new T) error,
+) (last T, err error) {
+	last = start
+	for {
+		next, _ := WaitForChangeFunc(ctx, last, source, eq)
+		if ctx.Err() != nil || (eq(next, last) && source.Closed()) {
+			return last, nil
+		}
+		if err := fn(ctx, last, next); err != nil {
+			return last, fmt.Errorf("changed [%v -> %v]: %w", last, next, err)
+		}
+		last = next
+	}
+}
+
+// DoWhenChangedOrInterval executes the callback when the variable has
+// changed or if the configured period of time has elapsed since the
+// last invocation. If an error is returned from the callback, the last
+// successfully-processed value will be returned. This function returns
+// once the source has been closed or the context has been canceled.
+func DoWhenChangedOrInterval[T comparable](
+	ctx context.Context,
+	start T,
+	source *Var[T],
+	period time.Duration,
+	fn func(ctx context.Context, old, new T) error,
+) (last T, err error) {
+	last = start
+	for {
+		next, _ := WaitForChangeOrDuration(ctx, last, source, period)
+		if ctx.Err() != nil || (next == last && source.Closed()) {
+			return last, nil
+		}
+		if err := fn(ctx, last, next); err != nil {
+			return last, fmt.Errorf("changed [%v -> %v]: %w", last, next, err)
+		}
+		last = next
+	}
+}
+
+// WaitForChange waits for the source to change to another value. If
+// the context is canceled or the source is closed, the most recent
+// value will be returned.
+func WaitForChange[T comparable](
+	ctx context.Context, current T, source *Var[T],
+) (next T, changed <-chan struct{}) {
+	return WaitForChangeFunc(ctx, current, source, func(a, b T) bool { return a == b })
+}
+
+// WaitForChangeFunc waits for the source to change to a value which is
+// not equal to the current value, as determined by the comparator. If
+// the context is canceled or the source is closed, the most recent
+// value will be returned.
+func WaitForChangeFunc[T any](
+	ctx context.Context, current T, source *Var[T], eq func(a, b T) bool,
+) (next T, changed <-chan struct{}) {
+	for {
+		entry, ch, closed := source.state()
+		next, changed = entry.Value, ch
+		if !eq(current, next) || closed {
+			return next, changed
+		}
+		select {
+		case <-changed:
+			continue
+		case <-ctx.Done():
+			return current, changed
+		}
+	}
+}
+
+// WaitForChangeOrDeadline waits for the source to change to another
+// value or for the deadline to pass. If the deadline has already
+// passed, this function returns immediately. If the context is canceled
+// or the source is closed, the most recent value will be returned.
+func WaitForChangeOrDeadline[T comparable](
+	ctx context.Context, current T, source *Var[T], deadline time.Time,
+) (next T, changed <-chan struct{}) {
+	entry, changed, closed := source.state()
+	next = entry.Value
+	if current != next || closed {
+		return next, changed
+	}
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return current, changed
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-changed:
+		case <-timer.C:
+			return current, changed
+		case <-ctx.Done():
+			return current, changed
+		}
+		entry, changed, closed = source.state()
+		next = entry.Value
+		if current != next || closed {
+			return next, changed
+		}
+	}
+}
+
+// WaitForChangeOrDuration waits for the source to change to another
+// value or for the given duration to elapse. A non-positive duration
+// causes this function to return immediately. If the context is
+// canceled or the source is closed, the most recent value will be
+// returned.
+func WaitForChangeOrDuration[T comparable](
+	ctx context.Context, current T, source *Var[T], d time.Duration,
+) (next T, changed <-chan struct{}) {
+	return WaitForChangeOrDeadline(ctx, current, source, time.Now().Add(d))
+}
+
+// WaitForValue waits until the source emits the requested value. An
+// error wrapping [ErrClosed] will be returned if the source is closed
+// before the value is observed. See also [Var.Wait] for waiting on an
+// arbitrary predicate.
+func WaitForValue[T comparable](ctx context.Context, expected T, source *Var[T]) error {
+	_, err := source.Wait(ctx, func(found T) bool { return found == expected })
+	return err
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoWhenChanged(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	seen := make(chan int)
+	done := make(chan int, 1)
+	go func() {
+		last, _ := DoWhenChanged(ctx, 0, v, func(_ context.Context, _, next int) error {
+			seen <- next
+			return nil
+		})
+		done <- last
+	}()
+
+	v.Set(1)
+	r.Equal(1, <-seen)
+	v.Set(1)
+	v.Set(2)
+	r.Equal(2, <-seen)
+
+	// Closing the source terminates the loop.
+	v.Close()
+	r.Equal(2, <-done)
+}
+
+func TestDoWhenChangedError(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	boom := errors.New("boom")
+	v := VarOf(0)
+	go v.Set(1)
+	last, err := DoWhenChanged(ctx, 0, v, func(context.Context, int, int) error {
+		return boom
+	})
+	r.ErrorIs(err, boom)
+	r.Equal(0, last)
+}
+
+func TestDoWhenChangedFunc(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf([]int{1})
+	go func() {
+		v.Set([]int{1})
+		v.Set([]int{1, 2})
+		v.Close()
+	}()
+	var seen [][]int
+	last, err := DoWhenChangedFunc(ctx, []int{1}, v, slices.Equal,
+		func(_ context.Context, _, next []int) error {
+			seen = append(seen, next)
+			return nil
+		})
+	r.NoError(err)
+	r.Equal([]int{1, 2}, last)
+	r.Equal([][]int{{1, 2}}, seen)
+}
+
+func TestDoWhenChangedOrInterval(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	v := VarOf(0)
+	count := 0
+	last, err := DoWhenChangedOrInterval(ctx, 0, v, time.Millisecond,
+		func(_ context.Context, old, next int) error {
+			r.Equal(old, next)
+			if count++; count == 3 {
+				cancel()
+			}
+			return nil
+		})
+	r.NoError(err)
+	r.Equal(0, last)
+	r.Equal(3, count)
+}
+
+func TestWaitForChange(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	go v.Set(1)
+	next, _ := WaitForChange(ctx, 0, v)
+	r.Equal(1, next)
+
+	// A canceled context returns the current value.
+	canceled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	next, _ = WaitForChange(canceled, 1, v)
+	r.Equal(1, next)
+
+	next, _ = WaitForChangeOrDuration(ctx, 1, v, time.Millisecond)
+	r.Equal(1, next)
+
+	// An expired deadline returns immediately, but still reports a
+	// change that has already occurred.
+	next, _ = WaitForChangeOrDeadline(ctx, 1, v, time.Now().Add(-time.Hour))
+	r.Equal(1, next)
+	next, _ = WaitForChangeOrDeadline(ctx, 0, v, time.Now().Add(-time.Hour))
+	r.Equal(1, next)
+	next, _ = WaitForChangeOrDuration(ctx, 1, v, -time.Hour)
+	r.Equal(1, next)
+
+	go v.Set(2)
+	next, _ = WaitForChangeOrDeadline(ctx, 1, v, time.Now().Add(time.Minute))
+	r.Equal(2, next)
+}
+
+func TestWaitForChangeFunc(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf([]string{"a"})
+	go func() {
+		// An equivalent slice is not a change.
+		v.Set([]string{"a"})
+		v.Set([]string{"a", "b"})
+	}()
+	next, _ := WaitForChangeFunc(ctx, []string{"a"}, v, slices.Equal)
+	r.Equal([]string{"a", "b"}, next)
+
+	// A closed source returns the current value.
+	v.Close()
+	next, _ = WaitForChangeFunc(ctx, next, v, slices.Equal)
+	r.Equal([]string{"a", "b"}, next)
+}
+
+func TestWaitForValue(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	go v.Set(1)
+	r.NoError(WaitForValue(ctx, 1, v))
+
+	v.Close()
+	r.ErrorIs(WaitForValue(ctx, 2, v), ErrClosed)
+}
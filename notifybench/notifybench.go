@@ -0,0 +1,188 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notifybench contains standardized benchmark scenarios for the
+// notify package. Each scenario has a regression threshold, which
+// allows the performance of the package to be validated on a
+// particular machine. Since wall-clock thresholds depend on the
+// hardware, checks should be opted into rather than run with every
+// test:
+//
+//	func TestPerformance(t *testing.T) {
+//		if os.Getenv("CHECK_PERFORMANCE") == "" {
+//			t.Skip("set CHECK_PERFORMANCE to enable")
+//		}
+//		for _, s := range notifybench.Scenarios() {
+//			if err := s.Check(); err != nil {
+//				t.Error(err)
+//			}
+//		}
+//	}
+//
+// The scenarios may also be executed as benchmarks:
+//
+//	func BenchmarkScenarios(b *testing.B) {
+//		for _, s := range notifybench.Scenarios() {
+//			b.Run(s.Name, func(b *testing.B) {
+//				elapsed, err := s.Run(b.N)
+//				if err != nil {
+//					b.Fatal(err)
+//				}
+//				b.ReportMetric(float64(elapsed.Nanoseconds())/float64(b.N), "ns/op")
+//			})
+//		}
+//	}
+package notifybench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"vawter.tech/notify"
+)
+
+// measureTarget is the minimum duration over which Measure will execute
+// a scenario, to amortize the cost of its setup.
+const measureTarget = time.Second
+
+// A Scenario is a standardized benchmark.
+type Scenario struct {
+	// MaxPerOp is the regression threshold enforced by Check.
+	MaxPerOp time.Duration
+	// Name identifies the scenario.
+	Name string
+	// Run executes n operations and returns the time that they took,
+	// excluding any setup.
+	Run func(n int) (elapsed time.Duration, err error)
+}
+
+// Check measures the scenario and returns an error if the time per
+// operation exceeds its threshold.
+func (s Scenario) Check() error {
+	perOp, err := s.Measure()
+	if err != nil {
+		return err
+	}
+	if perOp > s.MaxPerOp {
+		return fmt.Errorf("%s: %s per op exceeds threshold of %s", s.Name, perOp, s.MaxPerOp)
+	}
+	return nil
+}
+
+// Measure executes the scenario with an increasing number of operations
+// until it has run for at least one second, and then returns the time
+// taken per operation.
+func (s Scenario) Measure() (time.Duration, error) {
+	for n := 1; ; n *= 2 {
+		elapsed, err := s.Run(n)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		if elapsed >= measureTarget {
+			return elapsed / time.Duration(n), nil
+		}
+	}
+}
+
+// Scenarios returns the standard benchmark scenarios.
+func Scenarios() []Scenario {
+	return []Scenario{
+		{
+			MaxPerOp: 100 * time.Microsecond,
+			Name:     "writer/waiters=100",
+			Run:      func(n int) (time.Duration, error) { return Waiters(n, 100) },
+		},
+		{
+			MaxPerOp: time.Millisecond,
+			Name:     "aggregation/vars=1000",
+			Run:      func(n int) (time.Duration, error) { return Aggregation(n, 1000) },
+		},
+		{
+			MaxPerOp: 10 * time.Millisecond,
+			Name:     "fanout/derived=100",
+			Run:      func(n int) (time.Duration, error) { return FanOut(n, 100) },
+		},
+	}
+}
+
+// Aggregation measures the time taken to detect n changes to the given
+// number of variables in an [notify.Aggregation].
+func Aggregation(n, vars int) (time.Duration, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agg := notify.NewAggregation()
+	all := make([]*notify.Var[int], vars)
+	for i := range all {
+		all[i] = notify.VarOf(0)
+		notify.Aggregate(agg, all[i])
+	}
+
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		v := all[i%vars]
+		v.Set(i)
+		<-agg.Updated(ctx)
+		found, ok := agg.Choose()
+		if !ok || found != v {
+			return 0, errors.New("unexpected variable chosen")
+		}
+		notify.Aggregate(agg, v)
+	}
+	return time.Since(start), nil
+}
+
+// FanOut measures the time taken for n changes to the source to be
+// propagated to the given number of derived variables.
+func FanOut(n, derived int) (time.Duration, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := notify.VarOf(0)
+	outs := make([]*notify.Derived[int], derived)
+	for i := range outs {
+		outs[i] = notify.Map(ctx, source, func(i int) int { return i })
+	}
+
+	start := time.Now()
+	for i := 1; i <= n; i++ {
+		source.Set(i)
+		for _, out := range outs {
+			if _, err := out.Wait(ctx, func(found int) bool { return found == i }); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return time.Since(start), nil
+}
+
+// Waiters measures the time taken for a single writer to update a
+// variable n times while it is being watched by the given number of
+// goroutines.
+func Waiters(n, waiters int) (time.Duration, error) {
+	v := notify.VarOf(0)
+	var wg sync.WaitGroup
+	for range waiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, changed := v.Get()
+			for !v.Closed() {
+				<-changed
+				_, changed = v.Get()
+			}
+		}()
+	}
+
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		v.Set(i)
+	}
+	elapsed := time.Since(start)
+	v.Close()
+	wg.Wait()
+	return elapsed, nil
+}
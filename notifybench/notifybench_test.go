@@ -0,0 +1,49 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifybench
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkScenarios(b *testing.B) {
+	for _, s := range Scenarios() {
+		b.Run(s.Name, func(b *testing.B) {
+			elapsed, err := s.Run(b.N)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(float64(elapsed.Nanoseconds())/float64(b.N), "ns/op")
+		})
+	}
+}
+
+// TestScenarios verifies that each scenario runs, without enforcing
+// any thresholds.
+func TestScenarios(t *testing.T) {
+	for _, s := range Scenarios() {
+		t.Run(s.Name, func(t *testing.T) {
+			r := require.New(t)
+			elapsed, err := s.Run(10)
+			r.NoError(err)
+			r.Positive(elapsed)
+		})
+	}
+}
+
+// TestThresholds enforces the wall-clock thresholds, which depend on the
+// hardware, so it must be enabled by setting NOTIFYBENCH_CHECK.
+func TestThresholds(t *testing.T) {
+	if os.Getenv("NOTIFYBENCH_CHECK") == "" {
+		t.Skip("set NOTIFYBENCH_CHECK to enforce the thresholds")
+	}
+	for _, s := range Scenarios() {
+		t.Run(s.Name, func(t *testing.T) {
+			require.NoError(t, s.Check())
+		})
+	}
+}
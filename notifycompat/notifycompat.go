@@ -0,0 +1,106 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notifycompat contains adapters that allow existing
+// notification mechanisms to be observed as a [notify.Var]. This allows
+// the helpers in the notify and notifyx packages to be adopted without
+// first rewriting the code that produces notifications.
+package notifycompat
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"vawter.tech/notify"
+)
+
+// FromBroadcast adapts the close-to-broadcast idiom, in which a
+// producer closes a channel to wake listeners and then replaces it, to
+// a Var. The current function must return the producer's current
+// channel. The returned Var contains the number of broadcasts that
+// have been observed and will be updated until the context is canceled.
+// Broadcasts that occur in rapid succession may be coalesced. Since the
+// producer does not signal when the replacement channel is available,
+// the current function will be polled, with a backoff, until it returns
+// a different channel.
+func FromBroadcast(ctx context.Context, current func() <-chan struct{}) *notify.Var[uint64] {
+	const maxDelay = 10 * time.Millisecond
+	ret := notify.VarOf[uint64](0)
+	ch := current()
+	go func() {
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ch:
+			case <-ctx.Done():
+				return
+			}
+			_, _, _ = ret.Update(func(old uint64) (uint64, error) {
+				return old + 1, nil
+			})
+
+			// Wait for the producer to replace the closed channel.
+			prev := ch
+			for delay := time.Microsecond; ; delay = min(2*delay, maxDelay) {
+				if ch = current(); ch != prev {
+					break
+				}
+				timer.Reset(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ret
+}
+
+// FromCond adapts a [sync.Cond] to a Var. The read function will be
+// called, with the Cond's lock held, to sample a value each time the
+// Cond is signaled. The returned Var will be updated until the context
+// is canceled.
+func FromCond[T any](ctx context.Context, cond *sync.Cond, read func() T) *notify.Var[T] {
+	cond.L.Lock()
+	ret := notify.VarOf(read())
+	cond.L.Unlock()
+
+	// Wake the goroutine below when the context is canceled.
+	context.AfterFunc(ctx, func() {
+		cond.L.Lock()
+		defer cond.L.Unlock()
+		cond.Broadcast()
+	})
+
+	go func() {
+		cond.L.Lock()
+		defer cond.L.Unlock()
+		for {
+			// Read before waiting, in case a signal was missed.
+			ret.Set(read())
+			if ctx.Err() != nil {
+				return
+			}
+			cond.Wait()
+		}
+	}()
+	return ret
+}
+
+// OnceFunc is analogous to [sync.OnceFunc], but also returns a Var that
+// reports whether the function has completed. The Var will be closed
+// once the function has returned, so that consumers waiting for a
+// one-time event, such as the completion of initialization, may use the
+// helpers in the notify and notifyx packages. If the function panics,
+// the Var will not be updated.
+func OnceFunc(fn func()) (func(), *notify.Var[bool]) {
+	ret := notify.VarOf(false)
+	return sync.OnceFunc(func() {
+		fn()
+		ret.Set(true)
+		ret.Close()
+	}), ret
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifycompat
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromBroadcast(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var mu sync.Mutex
+	ch := make(chan struct{})
+	current := func() <-chan struct{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return ch
+	}
+	broadcast := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		close(ch)
+		ch = make(chan struct{})
+	}
+
+	v := FromBroadcast(ctx, current)
+	_, changed := v.Get()
+	broadcast()
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		r.NoError(ctx.Err())
+	}
+	r.NotZero(v.Load())
+}
+
+func TestFromBroadcastDelayedReplacement(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var mu sync.Mutex
+	ch := make(chan struct{})
+	current := func() <-chan struct{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return ch
+	}
+
+	v := FromBroadcast(ctx, current)
+	_, changed := v.Get()
+
+	// The closed channel is not replaced right away.
+	mu.Lock()
+	close(ch)
+	mu.Unlock()
+	<-changed
+	time.Sleep(10 * time.Millisecond)
+	r.Equal(uint64(1), v.Load())
+
+	mu.Lock()
+	ch = make(chan struct{})
+	close(ch)
+	mu.Unlock()
+	found, err := v.Wait(ctx, func(count uint64) bool { return count >= 2 })
+	r.NoError(err)
+	r.Equal(uint64(2), found)
+}
+
+func TestFromCond(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	state := 1
+
+	v := FromCond(ctx, cond, func() int { return state })
+	r.Equal(1, v.Load())
+
+	mu.Lock()
+	state = 2
+	cond.Broadcast()
+	mu.Unlock()
+
+	for found, changed := v.Get(); found != 2; found, changed = v.Get() {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			r.NoError(ctx.Err())
+		}
+	}
+}
+
+func TestOnceFunc(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	calls := 0
+	do, done := OnceFunc(func() { calls++ })
+	r.False(done.Load())
+
+	do()
+	do()
+	r.Equal(1, calls)
+	found, err := done.Wait(ctx, func(done bool) bool { return done })
+	r.NoError(err)
+	r.True(found)
+	r.True(done.Closed())
+}
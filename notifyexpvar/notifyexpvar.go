@@ -0,0 +1,25 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notifyexpvar publishes the value of a [notify.Var] using the
+// [expvar] package. It is separate from the notify package because
+// importing expvar registers the /debug/vars handler on
+// [net/http.DefaultServeMux].
+package notifyexpvar
+
+import (
+	"expvar"
+
+	"vawter.tech/notify"
+)
+
+// Publish publishes the current value of the Var using the [expvar]
+// package. The value is read lazily each time the exported variables
+// are requested, such as through the /debug/vars endpoint. The value
+// must be encodable as JSON. Like [expvar.Publish], this function will
+// panic if the name is already in use.
+func Publish[T any](name string, v *notify.Var[T]) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return v.Load()
+	}))
+}
@@ -0,0 +1,29 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyexpvar
+
+import (
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+)
+
+func TestPublish(t *testing.T) {
+	r := require.New(t)
+
+	type config struct {
+		Setting string
+	}
+	v := notify.VarOf(config{"a"})
+	Publish("notifyexpvar.TestPublish", v)
+
+	published := expvar.Get("notifyexpvar.TestPublish")
+	r.NotNil(published)
+	r.JSONEq(`{"Setting":"a"}`, published.String())
+
+	v.Set(config{"b"})
+	r.JSONEq(`{"Setting":"b"}`, published.String())
+}
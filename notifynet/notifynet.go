@@ -0,0 +1,158 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notifynet contains helpers that allow network configuration,
+// such as listeners, TLS certificates, or HTTP transports, to be driven
+// by a [notify.Var].
+package notifynet
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"vawter.tech/notify"
+	"vawter.tech/notify/internal/varstate"
+)
+
+// CertificateConfig returns a clone of the base configuration whose
+// GetCertificate callback returns the current value of the Var. This
+// allows a server's certificate to be rotated without restarting its
+// listener. A nil base configuration is permitted.
+func CertificateConfig(base *tls.Config, cert *notify.Var[*tls.Certificate]) *tls.Config {
+	var ret *tls.Config
+	if base == nil {
+		ret = &tls.Config{}
+	} else {
+		ret = base.Clone()
+	}
+	ret.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if found := cert.Load(); found != nil {
+			return found, nil
+		}
+		return nil, errors.New("no certificate available")
+	}
+	return ret
+}
+
+// ListenVar opens a TCP listener on the address and then starts a
+// goroutine to open a new listener whenever the address changes. The
+// new listener is stored in the returned Var before the old listener is
+// closed, so that servers which watch the Var may begin accepting
+// connections on the new address without an interruption in service.
+// If a new listener cannot be opened, the error will be logged and the
+// existing listener will be retained. When the context is canceled, the
+// current listener and the returned Var will be closed.
+func ListenVar(ctx context.Context, addr *notify.Var[string]) (*notify.Var[net.Listener], error) {
+	var cfg net.ListenConfig
+	current, changed := addr.Get()
+	ln, err := cfg.Listen(ctx, "tcp", current)
+	if err != nil {
+		return nil, err
+	}
+	out := notify.VarOf(ln)
+	go func() {
+		defer out.Close()
+		for {
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				_ = ln.Close()
+				return
+			}
+			var next string
+			next, changed = addr.Get()
+			if addr.Closed() {
+				// Retain the listener until the context is canceled.
+				changed = nil
+			}
+			if next == current {
+				continue
+			}
+			nextLn, err := cfg.Listen(ctx, "tcp", next)
+			if err != nil {
+				slog.ErrorContext(ctx, "could not open listener",
+					slog.String("addr", next), slog.Any("error", err))
+				continue
+			}
+			out.Set(nextLn)
+			_ = ln.Close()
+			current, ln = next, nextLn
+		}
+	}()
+	return out, nil
+}
+
+// ServerConfig returns a configuration whose GetConfigForClient
+// callback returns the current value of the Var. This allows every
+// aspect of a server's TLS configuration, such as client CAs or cipher
+// suites, to be changed for new connections at runtime.
+func ServerConfig(cfg *notify.Var[*tls.Config]) *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			if found := cfg.Load(); found != nil {
+				return found, nil
+			}
+			return nil, errors.New("no configuration available")
+		},
+	}
+}
+
+// Transport returns an [http.RoundTripper] that delegates to the
+// current value of the Var. When the Var changes, idle connections held
+// by the previous transport will be closed so that new requests pick up
+// changed settings, such as proxies, root CAs, or timeouts. The returned
+// RoundTripper will track the Var until the context is canceled or the
+// Var is closed.
+func Transport(ctx context.Context, source *notify.Var[*http.Transport]) http.RoundTripper {
+	go trackTransport(ctx, source)
+	return &transport{source}
+}
+
+// trackTransport closes the idle connections of the previous transport
+// whenever the Var changes. It returns once the context is canceled or
+// the Var is closed.
+func trackTransport(ctx context.Context, source *notify.Var[*http.Transport]) {
+	current, changed := source.Get()
+	for {
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+		var next *http.Transport
+		var closed bool
+		next, _, changed, closed = varstate.Load[*http.Transport](source)
+		if current != nil && current != next {
+			current.CloseIdleConnections()
+		}
+		current = next
+		if closed {
+			return
+		}
+	}
+}
+
+type transport struct {
+	source *notify.Var[*http.Transport]
+}
+
+// CloseIdleConnections allows [http.Client.CloseIdleConnections] to
+// operate on the current transport.
+func (t *transport) CloseIdleConnections() {
+	if current := t.source.Load(); current != nil {
+		current.CloseIdleConnections()
+	}
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	current := t.source.Load()
+	if current == nil {
+		return nil, errors.New("no transport available")
+	}
+	return current.RoundTrip(req)
+}
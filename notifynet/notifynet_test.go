@@ -0,0 +1,150 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifynet
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+)
+
+func TestCertificateConfig(t *testing.T) {
+	r := require.New(t)
+
+	cert := &notify.Var[*tls.Certificate]{}
+	cfg := CertificateConfig(&tls.Config{MinVersion: tls.VersionTLS13}, cert)
+	r.Equal(uint16(tls.VersionTLS13), cfg.MinVersion)
+
+	_, err := cfg.GetCertificate(nil)
+	r.Error(err)
+
+	first := &tls.Certificate{}
+	cert.Set(first)
+	found, err := cfg.GetCertificate(nil)
+	r.NoError(err)
+	r.Same(first, found)
+
+	second := &tls.Certificate{}
+	cert.Set(second)
+	found, err = cfg.GetCertificate(nil)
+	r.NoError(err)
+	r.Same(second, found)
+}
+
+func TestListenVar(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	addr := notify.VarOf("127.0.0.1:0")
+	lns, err := ListenVar(ctx, addr)
+	r.NoError(err)
+	first, changed := lns.Get()
+
+	// A bad address retains the current listener.
+	addr.Set("bad address")
+	addr.Set("localhost:0")
+	<-changed
+	second, changed := lns.Get()
+	r.NotEqual(first.Addr(), second.Addr())
+
+	// The old listener is closed after the new one is published.
+	_, err = first.Accept()
+	r.ErrorIs(err, net.ErrClosed)
+
+	conn, err := net.Dial(second.Addr().Network(), second.Addr().String())
+	r.NoError(err)
+	r.NoError(conn.Close())
+
+	cancel()
+	<-changed
+	r.True(lns.Closed())
+	_, err = second.Accept()
+	r.ErrorIs(err, net.ErrClosed)
+
+	// An initial bad address is reported.
+	_, err = ListenVar(context.Background(), notify.VarOf("bad address"))
+	r.Error(err)
+}
+
+func TestServerConfig(t *testing.T) {
+	r := require.New(t)
+
+	inner := &tls.Config{ServerName: "example.com"}
+	cfg := ServerConfig(notify.VarOf(inner))
+	found, err := cfg.GetConfigForClient(nil)
+	r.NoError(err)
+	r.Same(inner, found)
+}
+
+func TestTransport(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	// Count the connections made by each transport.
+	counting := func(count *atomic.Int32) *http.Transport {
+		var dialer net.Dialer
+		return &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				count.Add(1)
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}
+	}
+	var firstCount, secondCount atomic.Int32
+	source := notify.VarOf(counting(&firstCount))
+	client := &http.Client{Transport: Transport(ctx, source)}
+	defer client.CloseIdleConnections()
+
+	get := func() {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		r.NoError(err)
+		resp, err := client.Do(req)
+		r.NoError(err)
+		r.NoError(resp.Body.Close())
+	}
+
+	get()
+	r.Equal(int32(1), firstCount.Load())
+
+	source.Set(counting(&secondCount))
+	get()
+	r.Equal(int32(1), firstCount.Load())
+	r.Equal(int32(1), secondCount.Load())
+}
+
+func TestTransportClosed(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	source := notify.VarOf(&http.Transport{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		trackTransport(ctx, source)
+	}()
+
+	// Tracking stops once the source is closed, rather than spinning
+	// until the context is canceled.
+	source.Set(&http.Transport{})
+	source.Close()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		r.Fail("tracking did not stop")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notifyruntime contains helpers that allow the Go runtime to
+// be tuned or profiled while a process is running by a [notify.Var].
+package notifyruntime
+
+import (
+	"log/slog"
+	"math"
+	"runtime"
+	"runtime/debug"
+
+	"vawter.tech/notify"
+	"vawter.tech/notify/notifyx"
+	"vawter.tech/stopper"
+)
+
+// ClampGOMAXPROCS returns a value within [1, [runtime.NumCPU]]. Zero
+// or any negative value returns [runtime.NumCPU], which is the default
+// used by the Go runtime.
+func ClampGOMAXPROCS(procs int) int {
+	if procs <= 0 {
+		return runtime.NumCPU()
+	}
+	return min(procs, runtime.NumCPU())
+}
+
+// ClampGCPercent returns -1, which disables the garbage collector, for
+// any negative value.
+func ClampGCPercent(percent int) int {
+	return max(percent, -1)
+}
+
+// ClampMemoryLimit returns [math.MaxInt64], which disables the memory
+// limit, for any negative value.
+func ClampMemoryLimit(limit int64) int64 {
+	if limit < 0 {
+		return math.MaxInt64
+	}
+	return limit
+}
+
+// TuneGCPercent applies the clamped value of the variable with
+// [debug.SetGCPercent] and then starts a goroutine to reapply it
+// whenever it changes. See [ClampGCPercent].
+func TuneGCPercent(ctx *stopper.Context, percent *notify.Var[int]) {
+	tune(ctx, "GOGC", percent, ClampGCPercent, debug.SetGCPercent)
+}
+
+// TuneGOMAXPROCS applies the clamped value of the variable with
+// [runtime.GOMAXPROCS] and then starts a goroutine to reapply it
+// whenever it changes. See [ClampGOMAXPROCS].
+func TuneGOMAXPROCS(ctx *stopper.Context, procs *notify.Var[int]) {
+	tune(ctx, "GOMAXPROCS", procs, ClampGOMAXPROCS, runtime.GOMAXPROCS)
+}
+
+// TuneMemoryLimit applies the clamped value of the variable, in bytes,
+// with [debug.SetMemoryLimit] and then starts a goroutine to reapply it
+// whenever it changes. See [ClampMemoryLimit].
+func TuneMemoryLimit(ctx *stopper.Context, limit *notify.Var[int64]) {
+	tune(ctx, "GOMEMLIMIT", limit, ClampMemoryLimit, debug.SetMemoryLimit)
+}
+
+// tune applies the current value of the variable and then starts a
+// goroutine to reapply it whenever it changes. Every change to the
+// runtime is logged so that there is an audit trail of the adjustments
+// that were made. The goroutine will exit when the context is stopped.
+func tune[T comparable](
+	ctx *stopper.Context,
+	name string,
+	source *notify.Var[T],
+	clamp func(T) T,
+	apply func(T) T,
+) {
+	set := func(requested T) {
+		next := clamp(requested)
+		prev := apply(next)
+		if prev != next {
+			slog.InfoContext(ctx, "runtime setting changed",
+				slog.String("setting", name),
+				slog.Any("previous", prev),
+				slog.Any("requested", requested),
+				slog.Any("applied", next))
+		}
+	}
+
+	initial := source.Load()
+	set(initial)
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := notifyx.DoWhenChanged(ctx, initial, source,
+			func(_ *stopper.Context, _, next T) error {
+				set(next)
+				return nil
+			})
+		return err
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyruntime
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+	"vawter.tech/stopper"
+)
+
+func TestClamp(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(runtime.NumCPU(), ClampGOMAXPROCS(-5))
+	r.Equal(runtime.NumCPU(), ClampGOMAXPROCS(0))
+	r.Equal(1, ClampGOMAXPROCS(1))
+	r.Equal(runtime.NumCPU(), ClampGOMAXPROCS(math.MaxInt))
+
+	r.Equal(-1, ClampGCPercent(-100))
+	r.Equal(50, ClampGCPercent(50))
+
+	r.Equal(int64(math.MaxInt64), ClampMemoryLimit(-1))
+	r.Equal(int64(1024), ClampMemoryLimit(1024))
+}
+
+// readMetric returns the current value of a runtime metric without
+// modifying it.
+func readMetric(name string) uint64 {
+	samples := []metrics.Sample{{Name: name}}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64()
+}
+
+func TestTune(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	prevProcs := runtime.GOMAXPROCS(0)
+	prevPercent := debug.SetGCPercent(100)
+	prevLimit := debug.SetMemoryLimit(-1)
+	defer func() {
+		runtime.GOMAXPROCS(prevProcs)
+		debug.SetGCPercent(prevPercent)
+		debug.SetMemoryLimit(prevLimit)
+	}()
+
+	stop := stopper.WithContext(ctx)
+	procs := notify.VarOf(1)
+	percent := notify.VarOf(50)
+	limit := notify.VarOf(int64(1 << 30))
+	TuneGOMAXPROCS(stop, procs)
+	TuneGCPercent(stop, percent)
+	TuneMemoryLimit(stop, limit)
+
+	// The initial values are applied synchronously.
+	r.Equal(1, runtime.GOMAXPROCS(0))
+	r.Equal(uint64(50), readMetric("/gc/gogc:percent"))
+	r.Equal(uint64(1<<30), readMetric("/gc/gomemlimit:bytes"))
+
+	procs.Set(math.MaxInt)
+	percent.Set(-10)
+	limit.Set(-1)
+	r.Eventually(func() bool {
+		return runtime.GOMAXPROCS(0) == runtime.NumCPU()
+	}, time.Minute, time.Millisecond)
+	r.Eventually(func() bool {
+		// The metric reports -1 as an unsigned value.
+		return readMetric("/gc/gogc:percent") == math.MaxUint64
+	}, time.Minute, time.Millisecond)
+	r.Eventually(func() bool {
+		return readMetric("/gc/gomemlimit:bytes") == math.MaxInt64
+	}, time.Minute, time.Millisecond)
+
+	stop.Stop(time.Minute)
+	r.NoError(stop.Wait())
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyruntime
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+	"strings"
+	"time"
+
+	"vawter.tech/notify"
+	"vawter.tech/notify/notifyx"
+	"vawter.tech/stopper"
+)
+
+// ProfileCPU starts a goroutine which records a CPU profile into a new
+// file in the directory whenever the variable is true. At most keep
+// profiles will be retained in the directory; the oldest files will be
+// removed once a profile is complete. A non-positive keep value retains
+// all profiles. Any profile in progress will be completed when the
+// context is stopped.
+func ProfileCPU(ctx *stopper.Context, enabled *notify.Var[bool], dir string, keep int) {
+	toggle(ctx, "cpu", ".pprof", enabled, dir, keep, pprof.StartCPUProfile, pprof.StopCPUProfile)
+}
+
+// ProfileTrace starts a goroutine which records an execution trace into
+// a new file in the directory whenever the variable is true. The keep
+// argument behaves as in [ProfileCPU].
+func ProfileTrace(ctx *stopper.Context, enabled *notify.Var[bool], dir string, keep int) {
+	toggle(ctx, "trace", ".out", enabled, dir, keep, trace.Start, trace.Stop)
+}
+
+// toggle calls start and stop as the variable changes. Failures are
+// logged, rather than returned, so that a misconfigured profile does not
+// stop the enclosing context.
+func toggle(
+	ctx *stopper.Context,
+	name, ext string,
+	enabled *notify.Var[bool],
+	dir string,
+	keep int,
+	start func(io.Writer) error,
+	stop func(),
+) {
+	var out *os.File
+	set := func(on bool) {
+		if on == (out != nil) {
+			return
+		}
+		if !on {
+			stop()
+			if err := out.Close(); err != nil {
+				slog.ErrorContext(ctx, "could not close profile",
+					slog.String("profile", name), slog.Any("error", err))
+			}
+			slog.InfoContext(ctx, "profile complete",
+				slog.String("profile", name), slog.String("file", out.Name()))
+			out = nil
+			if err := prune(dir, name+"-", keep); err != nil {
+				slog.ErrorContext(ctx, "could not remove old profiles",
+					slog.String("profile", name), slog.Any("error", err))
+			}
+			return
+		}
+		f, err := os.Create(filepath.Join(dir,
+			fmt.Sprintf("%s-%s%s", name, time.Now().UTC().Format("20060102T150405.000000000"), ext)))
+		if err != nil {
+			slog.ErrorContext(ctx, "could not create profile",
+				slog.String("profile", name), slog.Any("error", err))
+			return
+		}
+		if err := start(f); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			slog.ErrorContext(ctx, "could not start profile",
+				slog.String("profile", name), slog.Any("error", err))
+			return
+		}
+		slog.InfoContext(ctx, "profile started",
+			slog.String("profile", name), slog.String("file", f.Name()))
+		out = f
+	}
+
+	ctx.Go(func(ctx *stopper.Context) error {
+		initial := enabled.Load()
+		set(initial)
+		defer set(false)
+		_, err := notifyx.DoWhenChanged(ctx, initial, enabled,
+			func(_ *stopper.Context, _, next bool) error {
+				set(next)
+				return nil
+			})
+		return err
+	})
+}
+
+// prune removes all but the newest keep files in the directory which
+// have the given prefix. The files are named such that they sort by
+// creation time.
+func prune(dir, prefix string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(e os.DirEntry) bool {
+		return e.IsDir() || !strings.HasPrefix(e.Name(), prefix)
+	})
+	for len(entries) > keep {
+		if err := os.Remove(filepath.Join(dir, entries[0].Name())); err != nil {
+			return err
+		}
+		entries = entries[1:]
+	}
+	return nil
+}
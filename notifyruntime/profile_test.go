@@ -0,0 +1,102 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyruntime
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+	"vawter.tech/stopper"
+)
+
+func TestProfile(t *testing.T) {
+	tcs := []struct {
+		name string
+		fn   func(*stopper.Context, *notify.Var[bool], string, int)
+	}{
+		{"cpu", ProfileCPU},
+		{"trace", ProfileTrace},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			dir := t.TempDir()
+			// complete waits for a single, non-empty profile to exist.
+			complete := func() string {
+				var name string
+				r.Eventually(func() bool {
+					entries, err := os.ReadDir(dir)
+					r.NoError(err)
+					if len(entries) != 1 {
+						return false
+					}
+					info, err := entries[0].Info()
+					r.NoError(err)
+					name = entries[0].Name()
+					return info.Size() > 0
+				}, time.Minute, time.Millisecond)
+				return name
+			}
+
+			stop := stopper.WithContext(ctx)
+			enabled := notify.VarOf(false)
+			tc.fn(stop, enabled, dir, 1)
+
+			enabled.Set(true)
+			r.Eventually(func() bool {
+				entries, _ := os.ReadDir(dir)
+				return len(entries) == 1
+			}, time.Minute, time.Millisecond)
+			enabled.Set(false)
+			first := complete()
+
+			// The older profile is removed once the next is complete.
+			enabled.Set(true)
+			r.Eventually(func() bool {
+				entries, _ := os.ReadDir(dir)
+				return len(entries) == 2
+			}, time.Minute, time.Millisecond)
+
+			// Stopping the context completes the profile.
+			stop.Stop(time.Minute)
+			r.NoError(stop.Wait())
+			second := complete()
+			r.NotEqual(first, second)
+
+			f, err := os.Open(filepath.Join(dir, second))
+			r.NoError(err)
+			defer f.Close()
+			data, err := io.ReadAll(f)
+			r.NoError(err)
+			r.NotEmpty(data)
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	for _, name := range []string{"cpu-1", "cpu-2", "cpu-3", "trace-1"} {
+		r.NoError(os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	r.NoError(prune(dir, "cpu-", 2))
+
+	entries, err := os.ReadDir(dir)
+	r.NoError(err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	r.Equal([]string{"cpu-2", "cpu-3", "trace-1"}, names)
+}
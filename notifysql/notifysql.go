@@ -0,0 +1,128 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notifysql contains helpers that allow a [sql.DB] to be
+// reconfigured at runtime by a [notify.Var].
+package notifysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+
+	"vawter.tech/notify"
+	"vawter.tech/notify/notifyx"
+	"vawter.tech/stopper"
+)
+
+// PoolSettings contains the tunable parameters of a [sql.DB]. The zero
+// values of each field have the same meaning as in the corresponding
+// sql.DB setter.
+type PoolSettings struct {
+	ConnMaxIdleTime time.Duration // See [sql.DB.SetConnMaxIdleTime].
+	ConnMaxLifetime time.Duration // See [sql.DB.SetConnMaxLifetime].
+	MaxIdleConns    int           // See [sql.DB.SetMaxIdleConns].
+	MaxOpenConns    int           // See [sql.DB.SetMaxOpenConns].
+}
+
+// Apply configures the database pool.
+func (s PoolSettings) Apply(db *sql.DB) {
+	db.SetConnMaxIdleTime(s.ConnMaxIdleTime)
+	db.SetConnMaxLifetime(s.ConnMaxLifetime)
+	db.SetMaxIdleConns(s.MaxIdleConns)
+	db.SetMaxOpenConns(s.MaxOpenConns)
+}
+
+// TunePool applies the current settings to the database pool and then
+// starts a goroutine to reapply the settings whenever they change. The
+// goroutine will exit when the context is stopped.
+func TunePool(ctx *stopper.Context, db *sql.DB, settings *notify.Var[PoolSettings]) {
+	initial := settings.Load()
+	initial.Apply(db)
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := notifyx.DoWhenChanged(ctx, initial, settings,
+			func(_ *stopper.Context, _, next PoolSettings) error {
+				next.Apply(db)
+				return nil
+			})
+		return err
+	})
+}
+
+// RotatingConnector returns a [driver.Connector] that opens connections
+// using the current value of the DSN variable. This allows credentials
+// to be rotated without reopening the [sql.DB]. Existing connections are
+// unaffected by a change to the DSN; [PoolSettings.ConnMaxLifetime] may
+// be used to bound their age. If the driver implements
+// [driver.DriverContext], its OpenConnector method will be called once
+// for each distinct DSN.
+func RotatingConnector(d driver.Driver, dsn *notify.Var[string]) driver.Connector {
+	return &rotatingConnector{driver: d, dsn: dsn}
+}
+
+type rotatingConnector struct {
+	driver driver.Driver
+	dsn    *notify.Var[string]
+
+	mu struct {
+		sync.Mutex
+		connector driver.Connector
+		dsn       string
+	}
+}
+
+// Connect implements [driver.Connector].
+func (c *rotatingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connector, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+// Driver implements [driver.Connector].
+func (c *rotatingConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// current returns a connector for the current DSN.
+func (c *rotatingConnector) current() (driver.Connector, error) {
+	dsn := c.dsn.Load()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mu.connector != nil && c.mu.dsn == dsn {
+		return c.mu.connector, nil
+	}
+
+	var connector driver.Connector
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		var err error
+		connector, err = dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		connector = &dsnConnector{c.driver, dsn}
+	}
+	c.mu.connector = connector
+	c.mu.dsn = dsn
+	return connector, nil
+}
+
+// dsnConnector adapts a driver that does not implement
+// [driver.DriverContext].
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
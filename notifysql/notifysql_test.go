@@ -0,0 +1,80 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+	"vawter.tech/stopper"
+)
+
+// fakeDriver records the DSNs that were used to open connections.
+type fakeDriver struct {
+	mu   sync.Mutex
+	dsns []string
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dsns = append(d.dsns, dsn)
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("unsupported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("unsupported") }
+func (fakeConn) IsValid() bool                       { return false }
+func (fakeConn) Ping(context.Context) error          { return nil }
+
+func TestRotatingConnector(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	d := &fakeDriver{}
+	dsn := notify.VarOf("user:first@host")
+	db := sql.OpenDB(RotatingConnector(d, dsn))
+	defer db.Close()
+
+	r.NoError(db.PingContext(ctx))
+	dsn.Set("user:second@host")
+	r.NoError(db.PingContext(ctx))
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r.Equal([]string{"user:first@host", "user:second@host"}, d.dsns)
+}
+
+func TestTunePool(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	db := sql.OpenDB(RotatingConnector(&fakeDriver{}, notify.VarOf("")))
+	defer db.Close()
+
+	settings := notify.VarOf(PoolSettings{MaxOpenConns: 1})
+	TunePool(stop, db, settings)
+	r.Equal(1, db.Stats().MaxOpenConnections)
+
+	settings.Set(PoolSettings{MaxOpenConns: 2})
+	r.Eventually(func() bool {
+		return db.Stats().MaxOpenConnections == 2
+	}, time.Minute, time.Millisecond)
+
+	stop.Stop(time.Minute)
+	r.NoError(stop.Wait())
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notifytest contains utilities for testing code that uses the
+// notify package.
+package notifytest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"vawter.tech/notify"
+)
+
+// Faulty wraps a [notify.Value] to inject delivery faults into writes.
+// This allows tests to verify that consumers tolerate imperfect
+// delivery of updates. Faults are consumed in the order in which writes
+// occur and apply to both Set and Update, except for reordering, which
+// applies only to Set.
+type Faulty[T any] struct {
+	notify.Value[T]
+
+	mu struct {
+		sync.Mutex
+		delay     time.Duration
+		delayNext int
+		dropNext  int
+		dupNext   int
+		held      []T // Writes withheld by ReorderNext.
+		reorder   int
+	}
+}
+
+var _ notify.Value[any] = (*Faulty[any])(nil)
+
+// NewFaulty wraps the Value.
+func NewFaulty[T any](v notify.Value[T]) *Faulty[T] {
+	return &Faulty[T]{Value: v}
+}
+
+// DelayNext causes the next n writes to be delayed by d before being
+// applied.
+func (f *Faulty[T]) DelayNext(n int, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.delay = d
+	f.mu.delayNext = n
+}
+
+// DropNext causes the next n writes to be silently discarded.
+func (f *Faulty[T]) DropNext(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.dropNext = n
+}
+
+// DuplicateNext causes the next n writes to be applied twice, resulting
+// in a spurious notification.
+func (f *Faulty[T]) DuplicateNext(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.dupNext = n
+}
+
+// ReorderNext causes the next n calls to Set to be withheld until the
+// last of them occurs, at which point they are applied in reverse
+// order. Consumers will therefore observe older values after newer
+// ones. Update is not subject to reordering, since its callback must
+// observe the current value.
+func (f *Faulty[T]) ReorderNext(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.reorder = n
+}
+
+// Set injects faults before delegating to the wrapped Value.
+func (f *Faulty[T]) Set(next T) <-chan struct{} {
+	drop, dup := f.fault()
+	_, ch := f.Get()
+	if drop {
+		return ch
+	}
+	apply, held := f.hold(next)
+	if !held {
+		apply = []T{next}
+	}
+	for _, value := range apply {
+		ch = f.Value.Set(value)
+		if dup {
+			ch = f.Value.Set(value)
+		}
+	}
+	return ch
+}
+
+// Update injects faults before delegating to the wrapped Value.
+func (f *Faulty[T]) Update(fn func(old T) (new T, _ error)) (T, <-chan struct{}, error) {
+	drop, dup := f.fault()
+	if drop {
+		ret, ch := f.Get()
+		return ret, ch, nil
+	}
+	ret, ch, err := f.Value.Update(fn)
+	if dup && err == nil {
+		ch = f.Value.Set(ret)
+	}
+	return ret, ch, err
+}
+
+// hold withholds the value if a reorder has been requested. Once the
+// last of the withheld values is received, all of them are returned in
+// the order in which they should be applied.
+func (f *Faulty[T]) hold(next T) (apply []T, held bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.mu.reorder <= 0 {
+		return nil, false
+	}
+	f.mu.reorder--
+	f.mu.held = append(f.mu.held, next)
+	if f.mu.reorder > 0 {
+		return nil, true
+	}
+	apply = f.mu.held
+	f.mu.held = nil
+	slices.Reverse(apply)
+	return apply, true
+}
+
+// fault consumes the pending faults for a single write, sleeping if a
+// delay has been requested.
+func (f *Faulty[T]) fault() (drop, dup bool) {
+	f.mu.Lock()
+	var delay time.Duration
+	if f.mu.delayNext > 0 {
+		f.mu.delayNext--
+		delay = f.mu.delay
+	}
+	if f.mu.dropNext > 0 {
+		f.mu.dropNext--
+		drop = true
+	}
+	if f.mu.dupNext > 0 {
+		f.mu.dupNext--
+		dup = true
+	}
+	f.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return drop, dup
+}
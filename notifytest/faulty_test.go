@@ -0,0 +1,52 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+)
+
+func TestFaulty(t *testing.T) {
+	r := require.New(t)
+
+	v := notify.VarOf(0)
+	f := NewFaulty[int](v)
+
+	f.DropNext(1)
+	f.Set(1)
+	r.Equal(0, v.Load())
+	f.Set(2)
+	r.Equal(2, v.Load())
+
+	f.DuplicateNext(1)
+	_, version, _ := v.GetVersioned()
+	f.Set(3)
+	_, next, _ := v.GetVersioned()
+	r.Equal(version+2, next)
+
+	f.DelayNext(1, 10*time.Millisecond)
+	start := time.Now()
+	_, _, err := f.Update(func(old int) (int, error) { return old + 1, nil })
+	r.NoError(err)
+	r.GreaterOrEqual(time.Since(start), 10*time.Millisecond)
+	r.Equal(4, v.Load())
+
+	// Reordered writes are applied in reverse once the last arrives.
+	v.EnableHistory(3)
+	f.ReorderNext(3)
+	f.Set(5)
+	f.Set(6)
+	r.Equal(4, v.Load())
+	f.Set(7)
+	r.Equal(5, v.Load())
+	var values []int
+	for _, entry := range v.History() {
+		values = append(values, entry.Value)
+	}
+	r.Equal([]int{7, 6, 5}, values)
+}
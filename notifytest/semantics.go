@@ -0,0 +1,136 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+)
+
+// CheckVarSemantics exercises a [notify.Value] implementation against
+// the documented behavior of [notify.Var]. The factory must return a
+// new, independent Value each time that it is called. This allows
+// third-party implementations to demonstrate conformance:
+//   - A write closes the channel previously returned by Get and
+//     replaces it with a new, open channel.
+//   - Failed or no-op updates do not close the channel.
+//   - Updates may be coalesced, but a reader will always observe the
+//     final value that was written, whether by Get or by Watch.
+func CheckVarSemantics(t *testing.T, factory func() notify.Value[int]) {
+	t.Helper()
+
+	t.Run("set", func(t *testing.T) {
+		r := require.New(t)
+		v := factory()
+
+		_, ch := v.Get()
+		r.NotNil(ch)
+		r.False(notify.Changed(ch), "channel should be open")
+
+		next := v.Set(1)
+		r.True(notify.Changed(ch), "channel should be closed")
+		r.False(notify.Changed(next), "channel should be open")
+
+		found, current := v.Get()
+		r.Equal(1, found)
+		r.Equal(1, v.Load())
+		r.Equal(next, current)
+
+		peeked, err := v.Peek(func(value int) error {
+			r.Equal(1, value)
+			return nil
+		})
+		r.NoError(err)
+		r.Equal(current, peeked)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		r := require.New(t)
+		v := factory()
+		v.Set(1)
+
+		found, ch, err := v.Update(func(old int) (int, error) {
+			r.Equal(1, old)
+			return 2, nil
+		})
+		r.NoError(err)
+		r.Equal(2, found)
+		r.False(notify.Changed(ch), "channel should be open")
+
+		found, unchanged, err := v.Update(func(int) (int, error) {
+			return -1, notify.ErrNoUpdate
+		})
+		r.NoError(err)
+		r.Equal(2, found)
+		r.Equal(ch, unchanged)
+
+		found, unchanged, err = v.Update(func(int) (int, error) {
+			return -1, errors.New("expected")
+		})
+		r.Error(err)
+		r.Equal(2, found)
+		r.Equal(ch, unchanged)
+		r.False(notify.Changed(ch), "channel should be open")
+	})
+
+	t.Run("final value delivered", func(t *testing.T) {
+		r := require.New(t)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		v := factory()
+		v.Set(0)
+
+		const final = 1000
+		go func() {
+			for i := 1; i <= final; i++ {
+				v.Set(i)
+			}
+		}()
+
+		last := -1
+		for found, ch := v.Get(); found != final; found, ch = v.Get() {
+			r.GreaterOrEqual(found, last, "values should not go backwards")
+			last = found
+			select {
+			case <-ch:
+			case <-ctx.Done():
+				r.NoError(ctx.Err(), "last saw %d", last)
+			}
+		}
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		r := require.New(t)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		v := factory()
+		v.Set(0)
+
+		ch := v.Watch(ctx)
+		r.Equal(0, <-ch)
+
+		const final = 1000
+		go func() {
+			for i := 1; i <= final; i++ {
+				v.Set(i)
+			}
+		}()
+
+		last := 0
+		for last != final {
+			select {
+			case found := <-ch:
+				r.Greater(found, last, "values should not go backwards")
+				last = found
+			case <-ctx.Done():
+				r.NoError(ctx.Err(), "last saw %d", last)
+			}
+		}
+	})
+}
@@ -0,0 +1,16 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifytest
+
+import (
+	"testing"
+
+	"vawter.tech/notify"
+)
+
+func TestCheckVarSemantics(t *testing.T) {
+	CheckVarSemantics(t, func() notify.Value[int] {
+		return &notify.Var[int]{}
+	})
+}
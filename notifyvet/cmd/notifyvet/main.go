@@ -0,0 +1,18 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Command notifyvet reports common misuses of the notify package. It
+// may be run directly or by way of go vet:
+//
+//	go install vawter.tech/notify/notifyvet/cmd/notifyvet@latest
+//	go vet -vettool=$(which notifyvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+	"vawter.tech/notify/notifyvet"
+)
+
+func main() {
+	singlechecker.Main(notifyvet.Analyzer)
+}
@@ -0,0 +1,10 @@
+module vawter.tech/notify/notifyvet
+
+go 1.24
+
+require golang.org/x/tools v0.34.0
+
+require (
+	golang.org/x/mod v0.25.0 // indirect
+	golang.org/x/sync v0.15.0 // indirect
+)
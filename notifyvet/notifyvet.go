@@ -0,0 +1,269 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notifyvet contains a static analyzer that reports common
+// misuses of the notify package. It may be used with go vet by way of
+// [golang.org/x/tools/go/analysis/singlechecker] or with any other
+// driver that accepts an [analysis.Analyzer].
+package notifyvet
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const notifyPath = "vawter.tech/notify"
+
+// Analyzer reports the following patterns:
+//   - Repeatedly calling Get on the same Var or Reader within a loop
+//     and discarding the notification channel. Load should be used
+//     instead.
+//   - Calling a method that locks a Var, such as Set or Load, from
+//     within the callback passed to its own Update method, which will
+//     deadlock.
+//   - Comparing notification channels, returned by methods of a Var or
+//     Reader, to one another instead of receiving from them.
+var Analyzer = &analysis.Analyzer{
+	Name:     "notifyvet",
+	Doc:      "report common misuses of vawter.tech/notify",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// lockingMethods are the Var and Reader methods that acquire the Var's
+// lock before returning. Since the lock is held for writing while an
+// Update callback runs and is not reentrant, calling any of these
+// methods from within the callback will deadlock.
+var lockingMethods = map[string]bool{
+	"Close":                 true,
+	"Closed":                true,
+	"CompareAndSwapVersion": true,
+	"EnableHistory":         true,
+	"Get":                   true,
+	"GetFresh":              true,
+	"GetVersioned":          true,
+	"History":               true,
+	"Load":                  true,
+	"Notify":                true,
+	"OnChange":              true,
+	"Peek":                  true,
+	"Set":                   true,
+	"SetVersioned":          true,
+	"Subscribe":             true,
+	"Swap":                  true,
+	"TrySet":                true,
+	"Update":                true,
+	"UpdateContext":         true,
+	"UpdateIf":              true,
+	"UpdateVersioned":       true,
+	"Wait":                  true,
+	"WatchEvents":           true,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// Find the variables which hold notification channels.
+	chans := make(map[types.Object]bool)
+	insp.Preorder([]ast.Node{
+		(*ast.AssignStmt)(nil),
+		(*ast.ValueSpec)(nil),
+	}, func(n ast.Node) {
+		var lhs, rhs []ast.Expr
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			lhs, rhs = n.Lhs, n.Rhs
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				lhs = append(lhs, name)
+			}
+			rhs = n.Values
+		}
+		if len(rhs) != 1 {
+			return
+		}
+		call, ok := ast.Unparen(rhs[0]).(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		if _, ok := varMethod(pass, call); !ok {
+			return
+		}
+		for _, expr := range lhs {
+			id, ok := expr.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			if obj := pass.TypesInfo.ObjectOf(id); obj != nil && isNotificationChan(obj.Type()) {
+				chans[obj] = true
+			}
+		}
+	})
+
+	filter := []ast.Node{
+		(*ast.AssignStmt)(nil),
+		(*ast.BinaryExpr)(nil),
+		(*ast.CallExpr)(nil),
+	}
+	insp.WithStack(filter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			checkDiscardedChannel(pass, n, stack)
+		case *ast.BinaryExpr:
+			checkChannelComparison(pass, n, chans)
+		case *ast.CallExpr:
+			checkLockInUpdate(pass, n)
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// checkDiscardedChannel reports v, _ := x.Get() within a loop, where x
+// is declared outside of the loop. Reading each of several variables
+// once within a loop is not reported.
+func checkDiscardedChannel(pass *analysis.Pass, n *ast.AssignStmt, stack []ast.Node) {
+	if len(n.Lhs) != 2 || len(n.Rhs) != 1 {
+		return
+	}
+	if id, ok := n.Lhs[1].(*ast.Ident); !ok || id.Name != "_" {
+		return
+	}
+	call, ok := n.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return
+	}
+	if name, ok := varMethod(pass, call); !ok || name != "Get" {
+		return
+	}
+	recv := receiver(pass, call)
+	if recv == nil {
+		return
+	}
+	// Find the innermost loop.
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].(type) {
+		case *ast.FuncLit:
+			return
+		case *ast.ForStmt, *ast.RangeStmt:
+			if recv.Pos() < stack[i].Pos() {
+				pass.Reportf(n.Pos(),
+					"notification channel from Get is discarded within a loop; use Load instead")
+			}
+			return
+		}
+	}
+}
+
+// checkChannelComparison reports a == b or a != b, where both operands
+// are notification channels returned by a Var or Reader. Other
+// channels, such as those returned by [context.Context.Done], are not
+// reported.
+func checkChannelComparison(pass *analysis.Pass, n *ast.BinaryExpr, chans map[types.Object]bool) {
+	if n.Op != token.EQL && n.Op != token.NEQ {
+		return
+	}
+	isNotify := func(expr ast.Expr) bool {
+		switch expr := ast.Unparen(expr).(type) {
+		case *ast.CallExpr:
+			_, ok := varMethod(pass, expr)
+			return ok && isNotificationChan(pass.TypesInfo.TypeOf(expr))
+		case *ast.Ident:
+			return chans[pass.TypesInfo.ObjectOf(expr)]
+		default:
+			return false
+		}
+	}
+	if isNotify(n.X) && isNotify(n.Y) {
+		pass.Reportf(n.Pos(),
+			"notification channels should be received from, not compared; see notify.Changed")
+	}
+}
+
+// checkLockInUpdate reports v.Update(func(...) { v.Set(...) }).
+func checkLockInUpdate(pass *analysis.Pass, n *ast.CallExpr) {
+	name, ok := varMethod(pass, n)
+	if !ok || !strings.HasPrefix(name, "Update") {
+		return
+	}
+	recv := receiver(pass, n)
+	if recv == nil {
+		return
+	}
+	for _, arg := range n.Args {
+		lit, ok := arg.(*ast.FuncLit)
+		if !ok {
+			continue
+		}
+		ast.Inspect(lit.Body, func(node ast.Node) bool {
+			inner, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if name, ok := varMethod(pass, inner); ok && lockingMethods[name] && receiver(pass, inner) == recv {
+				pass.Reportf(inner.Pos(),
+					"calling %s on a Var from within its Update callback will deadlock", name)
+			}
+			return true
+		})
+	}
+}
+
+// isNotificationChan returns true if the type is <-chan struct{}.
+func isNotificationChan(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	ch, ok := t.Underlying().(*types.Chan)
+	if !ok || ch.Dir() != types.RecvOnly {
+		return false
+	}
+	st, ok := ch.Elem().Underlying().(*types.Struct)
+	return ok && st.NumFields() == 0
+}
+
+// receiver returns the object of a method call's receiver, if it is a
+// simple identifier.
+func receiver(pass *analysis.Pass, call *ast.CallExpr) types.Object {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	id, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	return pass.TypesInfo.ObjectOf(id)
+}
+
+// varMethod returns the name of the method if the call is to a method
+// of notify.Var or notify.Reader.
+func varMethod(pass *analysis.Pass, call *ast.CallExpr) (string, bool) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != notifyPath {
+		return "", false
+	}
+	recv := fn.Signature().Recv()
+	if recv == nil {
+		return "", false
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || (named.Obj().Name() != "Var" && named.Obj().Name() != "Reader") {
+		return "", false
+	}
+	return fn.Name(), true
+}
@@ -0,0 +1,57 @@
+package a
+
+import (
+	"context"
+
+	"vawter.tech/notify"
+)
+
+func discarded(v *notify.Var[int], r notify.Reader[int]) {
+	for i := 0; i < 10; i++ {
+		x, _ := v.Get() // want "notification channel from Get is discarded within a loop"
+		_ = x
+		y, _ := r.Get() // want "notification channel from Get is discarded within a loop"
+		_ = y
+	}
+	x, _ := v.Get() // OK outside of a loop.
+	_ = x
+}
+
+func eachOnce(vars []*notify.Var[int]) {
+	for _, v := range vars {
+		x, _ := v.Get() // OK, since each Var is read once.
+		_ = x
+	}
+	for _, found := range []any{vars[0]} {
+		x, _ := found.(*notify.Var[int]).Get() // OK, not a simple receiver.
+		_ = x
+	}
+}
+
+func compared(v *notify.Var[int]) bool {
+	_, a := v.Get()
+	_, b := v.Get()
+	if a == nil { // OK to compare with nil.
+		return false
+	}
+	c := v.Set(1)
+	if a == c { // want "notification channels should be received from"
+		return true
+	}
+	return a != b // want "notification channels should be received from"
+}
+
+func contexts(ctx, other context.Context) bool {
+	return ctx.Done() == other.Done() // OK, not notification channels.
+}
+
+func deadlock(v, other *notify.Var[int]) {
+	_, _, _ = v.Update(func(old int) (int, error) {
+		v.Set(old)     // want "calling Set on a Var from within its Update callback will deadlock"
+		_ = v.Load()   // want "calling Load on a Var from within its Update callback will deadlock"
+		_, _ = v.Get() // want "calling Get on a Var from within its Update callback will deadlock"
+		_ = other.Load()
+		other.Set(old)
+		return old, nil
+	})
+}
@@ -0,0 +1,15 @@
+// Package notify is a minimal stub for analyzer tests.
+package notify
+
+type Var[T any] struct{}
+
+func (v *Var[T]) Get() (T, <-chan struct{}) { panic("stub") }
+func (v *Var[T]) Load() T                   { panic("stub") }
+func (v *Var[T]) Set(T) <-chan struct{}     { panic("stub") }
+func (v *Var[T]) Update(fn func(T) (T, error)) (T, <-chan struct{}, error) {
+	panic("stub")
+}
+
+type Reader[T any] struct{}
+
+func (r Reader[T]) Get() (T, <-chan struct{}) { panic("stub") }
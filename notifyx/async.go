@@ -0,0 +1,155 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyx
+
+import (
+	"context"
+	"fmt"
+
+	"vawter.tech/notify"
+	"vawter.tech/notify/internal/varstate"
+	"vawter.tech/stopper"
+)
+
+// Busy determines what [DoWhenChangedAsync] does when the variable
+// changes while all workers are executing callbacks.
+type Busy int
+
+const (
+	// BusyQueueLatest executes the callback with the most recent value
+	// once a worker becomes available. Intermediate values are skipped.
+	BusyQueueLatest Busy = iota
+	// BusyCancel cancels the canceled contexts of the executing
+	// callbacks with [ErrSuperseded], since their values are stale, and
+	// then behaves as BusyQueueLatest.
+	BusyCancel
+	// BusyDrop ignores the change. The callback will next be executed
+	// when the variable changes while a worker is available.
+	BusyDrop
+)
+
+// DoWhenChangedAsync executes the callback on a pool of workers when the
+// variable has changed to a different value. Unlike [DoWhenChanged],
+// changes continue to be detected while callbacks are executing, which
+// is useful when the callback is slow. If a change is detected while
+// all workers are busy, the policy determines whether the change is
+// queued, cancels the executing callbacks, or is dropped. Each callback
+// receives the value passed to the previously-started callback as its
+// old value.
+//
+// Each callback receives the caller's stopper, so that it may stop the
+// loop or start tasks which outlive the callback, and a separate
+// context that is canceled if the callback should abandon its work.
+// Any error returned from a callback whose canceled context has been
+// canceled will be ignored.
+//
+// If an error is returned from a callback that was not canceled, the
+// remaining callbacks will be canceled and the error will be returned
+// once they have exited, along with the value of the most recent
+// callback to have succeeded. This function returns once the context
+// is stopping, or once the source has been closed and its final value
+// has been processed.
+func DoWhenChangedAsync[T comparable](
+	ctx *stopper.Context,
+	start T,
+	source *notify.Var[T],
+	workers int,
+	policy Busy,
+	fn func(ctx *stopper.Context, canceled context.Context, old, new T) error,
+) (last T, err error) {
+	type job struct {
+		cancel   context.CancelCauseFunc
+		canceled bool
+		old, new T
+	}
+	type result struct {
+		job *job
+		err error
+	}
+
+	workers = max(workers, 1)
+	last = start
+	dispatched := start // The value passed to the most recent job.
+	var pending *T      // A value waiting for a worker.
+	running := make(map[*job]struct{}, workers)
+	results := make(chan result)
+
+	launch := func(next T) {
+		canceled, cancel := context.WithCancelCause(ctx)
+		j := &job{cancel: cancel, old: dispatched, new: next}
+		running[j] = struct{}{}
+		dispatched = next
+		go func() {
+			results <- result{j, fn(ctx, canceled, j.old, j.new)}
+		}()
+	}
+	cancelAll := func(cause error) {
+		for j := range running {
+			j.canceled = true
+			j.cancel(cause)
+		}
+	}
+
+	next, _, changed, closed := varstate.Load[T](source)
+	observed := true // Set when a new value has been read.
+	stopping := ctx.Stopping()
+	for {
+		// Compare to the most recent value that will be processed.
+		target := dispatched
+		if pending != nil {
+			target = *pending
+		}
+		if observed && next != target && stopping != nil {
+			switch {
+			case len(running) < workers:
+				pending = nil
+				launch(next)
+			case policy == BusyCancel:
+				cancelAll(ErrSuperseded)
+				pending = &next
+			case policy == BusyQueueLatest:
+				pending = &next
+			}
+		}
+		observed = false
+		if closed {
+			// Avoid spinning on the closed channel.
+			changed = nil
+		}
+		if pending == nil && len(running) == 0 && (closed || stopping == nil) {
+			return last, err
+		}
+
+		select {
+		case <-changed:
+			next, _, changed, closed = varstate.Load[T](source)
+			observed = true
+		case res := <-results:
+			res.job.cancel(nil)
+			delete(running, res.job)
+			switch {
+			case res.job.canceled:
+			case res.err != nil:
+				if err == nil {
+					err = fmt.Errorf("changed [%v -> %v]: %w", res.job.old, res.job.new, res.err)
+				}
+				// Drain the remaining workers.
+				cancelAll(nil)
+				pending = nil
+				stopping, changed = nil, nil
+			default:
+				last = res.job.new
+			}
+			if pending != nil && len(running) < workers {
+				value := *pending
+				pending = nil
+				launch(value)
+			}
+		case <-stopping:
+			cancelAll(nil)
+			pending = nil
+			stopping, changed = nil, nil
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+	"vawter.tech/stopper"
+)
+
+func TestDoWhenChangedAsync(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	t.Run("queue latest", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		entered := make(chan int)
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var seen [][2]int
+		stop.Go(func(stop *stopper.Context) error {
+			_, err := DoWhenChangedAsync(stop, 0, v, 1, BusyQueueLatest,
+				func(_ *stopper.Context, _ context.Context, old, new int) error {
+					mu.Lock()
+					seen = append(seen, [2]int{old, new})
+					mu.Unlock()
+					entered <- new
+					<-release
+					return nil
+				})
+			stop.Stop(time.Minute) // The source has been closed.
+			return err
+		})
+
+		v.Set(1)
+		r.Equal(1, <-entered)
+		// These changes arrive while the only worker is busy.
+		v.Set(2)
+		v.Set(3)
+		v.Close()
+		time.Sleep(10 * time.Millisecond)
+		release <- struct{}{}
+		r.Equal(3, <-entered)
+		release <- struct{}{}
+		r.NoError(stop.Wait())
+		r.Equal([][2]int{{0, 1}, {1, 3}}, seen)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		entered := make(chan int)
+		canceled := make(chan int, 1)
+		var last int
+		stop.Go(func(stop *stopper.Context) error {
+			var err error
+			last, err = DoWhenChangedAsync(stop, 0, v, 1, BusyCancel,
+				func(_ *stopper.Context, superseded context.Context, _, new int) error {
+					entered <- new
+					if new == 2 {
+						return nil
+					}
+					<-superseded.Done()
+					if context.Cause(superseded) != ErrSuperseded {
+						return errors.New("unexpected cause")
+					}
+					canceled <- new
+					return errors.New("ignored because canceled")
+				})
+			stop.Stop(time.Minute) // The source has been closed.
+			return err
+		})
+
+		v.Set(1)
+		r.Equal(1, <-entered)
+		v.Set(2)
+		r.Equal(1, <-canceled)
+		r.Equal(2, <-entered)
+		v.Close()
+		r.NoError(stop.Wait())
+		r.Equal(2, last)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		entered := make(chan int)
+		release := make(chan struct{})
+		var last int
+		stop.Go(func(stop *stopper.Context) error {
+			var err error
+			last, err = DoWhenChangedAsync(stop, 0, v, 1, BusyDrop,
+				func(_ *stopper.Context, _ context.Context, _, new int) error {
+					entered <- new
+					<-release
+					return nil
+				})
+			stop.Stop(time.Minute) // The source has been closed.
+			return err
+		})
+
+		v.Set(1)
+		r.Equal(1, <-entered)
+		v.Set(2) // Dropped.
+		time.Sleep(10 * time.Millisecond)
+		release <- struct{}{}
+
+		// Changes are dropped until the worker has reported its result.
+		got := 0
+		for next := 3; got == 0; next++ {
+			v.Set(next)
+			select {
+			case got = <-entered:
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		r.Greater(got, 2)
+		release <- struct{}{}
+		v.Close()
+		r.NoError(stop.Wait())
+		r.Equal(got, last)
+	})
+
+	t.Run("workers", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		entered := make(chan int)
+		release := make(chan struct{})
+		stop.Go(func(stop *stopper.Context) error {
+			_, err := DoWhenChangedAsync(stop, 0, v, 2, BusyQueueLatest,
+				func(_ *stopper.Context, _ context.Context, _, new int) error {
+					entered <- new
+					<-release
+					return nil
+				})
+			stop.Stop(time.Minute) // The source has been closed.
+			return err
+		})
+
+		v.Set(1)
+		r.Equal(1, <-entered)
+		// A second worker is available.
+		v.Set(2)
+		r.Equal(2, <-entered)
+		close(release)
+		v.Close()
+		r.NoError(stop.Wait())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		v.Set(1)
+		last, err := DoWhenChangedAsync(stop, 0, v, 4, BusyQueueLatest,
+			func(*stopper.Context, context.Context, int, int) error {
+				return errors.New("boom")
+			})
+		r.ErrorContains(err, "changed [0 -> 1]: boom")
+		r.Equal(0, last)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		entered := make(chan struct{})
+		stop.Go(func(stop *stopper.Context) error {
+			_, err := DoWhenChangedAsync(stop, 0, v, 1, BusyQueueLatest,
+				func(ctx *stopper.Context, _ context.Context, _, _ int) error {
+					close(entered)
+					<-ctx.Stopping()
+					return nil
+				})
+			return err
+		})
+
+		v.Set(1)
+		<-entered
+		stop.Stop(time.Minute)
+		r.NoError(stop.Wait())
+	})
+}
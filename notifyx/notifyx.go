@@ -3,31 +3,175 @@
 // SPDX-License-Identifier: Apache-2.0
 
 // Package notifyx contains helpers that build on both the stopper and
-// notify packages.
+// notify packages. Variants of the most common helpers which accept a
+// plain [context.Context] are available in the notify package.
 package notifyx
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"vawter.tech/notify"
+	"vawter.tech/notify/internal/varstate"
 	"vawter.tech/stopper"
 )
 
+// ErrSuperseded is the cause of the cancellation of the context passed
+// to the callback of [DoWhenChangedLatest], or of [DoWhenChangedAsync]
+// when using [BusyCancel], once a newer value is available.
+var ErrSuperseded = errors.New("superseded by a newer value")
+
+// Apply stores the next value in the source and then invokes the
+// callback to act upon it. If the callback returns an error, the source
+// will be rolled back to its previous value, provided that no other
+// caller has changed the source in the interim. This keeps the variable
+// consistent with whatever external state the callback manages. If the
+// next value cannot be stored, the callback will not be invoked.
+func Apply[T comparable](
+	ctx *stopper.Context,
+	source *notify.Var[T],
+	next T,
+	fn func(ctx *stopper.Context, value T) error,
+) error {
+	var prev T
+	_, version, _, err := source.UpdateVersioned(func(old T) (T, error) {
+		prev = old
+		return next, nil
+	})
+	if err != nil {
+		return fmt.Errorf("apply [%v -> %v]: %w", prev, next, err)
+	}
+	if err := fn(ctx, next); err != nil {
+		// Compare versions, since the source may have been changed to
+		// another value and then back to next.
+		source.CompareAndSwapVersion(version, prev)
+		return fmt.Errorf("apply [%v -> %v]: %w", prev, next, err)
+	}
+	return nil
+}
+
+// A Condition is a predicate on the value of a variable that is
+// evaluated by [WaitForValues]. Conditions are constructed by
+// [ValueIs] or [ValueMatches].
+type Condition interface {
+	// check evaluates the condition. It returns a channel that will be
+	// closed when the underlying variable changes and, if the condition
+	// is not satisfied, an error describing the failure. The error will
+	// wrap [notify.ErrClosed] if the condition can never be satisfied.
+	check() (changed <-chan struct{}, err error)
+}
+
+type condition[T any] struct {
+	expected string
+	pred     func(T) bool
+	source   *notify.Var[T]
+}
+
+func (c *condition[T]) check() (<-chan struct{}, error) {
+	found, _, changed, closed := varstate.Load[T](c.source)
+	if c.pred(found) {
+		return changed, nil
+	}
+	if closed {
+		return changed, fmt.Errorf("%w, last saw %v while expecting %s",
+			notify.ErrClosed, found, c.expected)
+	}
+	return changed, fmt.Errorf("last saw %v while expecting %s", found, c.expected)
+}
+
+// A TimeoutError is returned by [WaitForChangeTimeout] and
+// [WaitForValueTimeout] to report the last value that was observed
+// before the timeout elapsed.
+type TimeoutError[T any] struct {
+	Last    T
+	Timeout time.Duration
+}
+
+func (e *TimeoutError[T]) Error() string {
+	return fmt.Sprintf("timed out after %s, last saw %v", e.Timeout, e.Last)
+}
+
+// ValueIs returns a Condition which is satisfied when the source
+// contains the expected value.
+func ValueIs[T comparable](source *notify.Var[T], expected T) Condition {
+	return &condition[T]{
+		expected: fmt.Sprint(expected),
+		pred:     func(found T) bool { return found == expected },
+		source:   source,
+	}
+}
+
+// ValueMatches returns a Condition which is satisfied when the value of
+// the source satisfies the predicate.
+func ValueMatches[T any](source *notify.Var[T], pred func(T) bool) Condition {
+	return &condition[T]{
+		expected: "a match",
+		pred:     pred,
+		source:   source,
+	}
+}
+
+// A Divergence is a report emitted by [VerifyAgainst].
+type Divergence[T comparable] struct {
+	Checked  time.Time // The time at which the comparison was completed.
+	Diverged bool      // True if Local and Remote differ.
+	Err      error     // Set if the remote value could not be fetched.
+	Local    T         // The value of the local variable.
+	Remote   T         // The value returned from the remote source.
+}
+
 // DoWhenChanged executes the callback when the variable has changed to
 // a different value. That is, if the variable is set to existing value,
 // the callback will not be invoked. If an error is returned from the
 // callback, the last successfully-processed value will be returned.
+// This function returns once the source has been closed.
 func DoWhenChanged[T comparable](
 	ctx *stopper.Context,
 	start T,
 	source *notify.Var[T],
 	fn func(ctx *stopper.Context, old, new T) error,
+) (last T, err error) {
+	return DoWhenChangedFunc(ctx, start, source, func(a, b T) bool { return a == b }, fn)
+}
+
+// DoWhenChangedDebounced executes the callback once the variable has
+// changed to a different value and has then remained unchanged for the
+// quiet period. A burst of changes is coalesced into a single
+// invocation of the callback with the final value. If an error is
+// returned from the callback, the last successfully-processed value
+// will be returned. This function returns once the source has been
+// closed and any pending value has been processed.
+func DoWhenChangedDebounced[T comparable](
+	ctx *stopper.Context,
+	start T,
+	source *notify.Var[T],
+	quiet time.Duration,
+	fn func(ctx *stopper.Context, old, new T) error,
+) (last T, err error) {
+	debounced := notify.Debounce(ctx, source, quiet)
+	defer debounced.Close()
+	return DoWhenChanged(ctx, start, debounced.Var, fn)
+}
+
+// DoWhenChangedFunc executes the callback when the variable has changed
+// to a value which is not equal to the previous value, as determined by
+// the comparator. This allows non-comparable types to be used. If an
+// error is returned from the callback, the last successfully-processed
+// value will be returned. This function returns once the source has
+// been closed.
+func DoWhenChangedFunc[T any](
+	ctx *stopper.Context,
+	start T,
+	source *notify.Var[T],
+	eq func(a, b T) bool,
+	fn func(ctx *stopper.Context, old, new T) error,
 ) (last T, err error) {
 	last = start
 	for {
-		next, _ := WaitForChange(ctx, last, source)
-		if ctx.IsStopping() {
+		next, _ := WaitForChangeFunc(ctx, last, source, eq)
+		if ctx.IsStopping() || (eq(next, last) && source.Closed()) {
 			return last, nil
 		}
 		if err := fn(ctx, last, next); err != nil {
@@ -37,12 +181,52 @@ func DoWhenChanged[T comparable](
 	}
 }
 
+// DoWhenChangedLatest is analogous to [DoWhenChanged], except that the
+// callback also receives a superseded context, which will be canceled
+// with [ErrSuperseded] as soon as the variable changes to yet another
+// value. This allows long-running work to be abandoned once its input
+// is stale. The superseded context is derived from, but otherwise
+// independent of, the stopper, which is passed to the callback as-is.
+// If the callback returns an error wrapping [context.Canceled] or
+// [ErrSuperseded] after the superseded context was canceled, the error
+// is ignored and the callback will be executed again, with the same old
+// value and the newer value. If any other error is returned from the
+// callback, the last successfully-processed value will be returned.
+// This function returns once the source has been closed.
+func DoWhenChangedLatest[T comparable](
+	ctx *stopper.Context,
+	start T,
+	source *notify.Var[T],
+	fn func(ctx *stopper.Context, superseded context.Context, old, new T) error,
+) (last T, err error) {
+	last = start
+	for {
+		next, changed := WaitForChange(ctx, last, source)
+		if ctx.IsStopping() || (next == last && source.Closed()) {
+			return last, nil
+		}
+		superseded, cancel := supersede(ctx, next, changed, source)
+		err := fn(ctx, superseded, last, next)
+		stale := context.Cause(superseded) == ErrSuperseded
+		cancel()
+		if err != nil {
+			if stale && !ctx.IsStopping() &&
+				(errors.Is(err, context.Canceled) || errors.Is(err, ErrSuperseded)) {
+				continue
+			}
+			return last, fmt.Errorf("changed [%v -> %v]: %w", last, next, err)
+		}
+		last = next
+	}
+}
+
 // DoWhenChangedOrInterval executes the callback when the variable has
 // changed or if the configured period of time has elapsed since the
 // last invocation. This is useful when some activity should be taken in
 // response to a change or at a somewhat regular interval. If an error
 // is returned from the callback, the last successfully-processed value
-// will be returned.
+// will be returned. This function returns once the source has been
+// closed.
 func DoWhenChangedOrInterval[T comparable](
 	ctx *stopper.Context,
 	start T,
@@ -53,9 +237,134 @@ func DoWhenChangedOrInterval[T comparable](
 	last = start
 	for {
 		next, _ := WaitForChangeOrDuration(ctx, last, source, period)
+		if ctx.IsStopping() || (next == last && source.Closed()) {
+			return last, nil
+		}
+		if err := fn(ctx, last, next); err != nil {
+			return last, fmt.Errorf("changed [%v -> %v]: %w", last, next, err)
+		}
+		last = next
+	}
+}
+
+// DoWhenEach executes the callback for every value that is stored in
+// the variable, starting with its current value. Unlike
+// [DoWhenChanged], which may coalesce intermediate values, the values
+// are received from a [notify.Subscription], so that each transition is
+// observed. At least buffer values may accumulate while the callback is
+// running; writers to the variable are never blocked. If the callback
+// falls further behind, an error wrapping [notify.ErrOverflow] will be
+// returned once the buffered values have been processed. If an error
+// is returned from the callback, this function will return it.
+// Otherwise, this function returns once the source has been closed and
+// all values have been processed.
+func DoWhenEach[T any](
+	ctx *stopper.Context,
+	source *notify.Var[T],
+	buffer int,
+	fn func(ctx *stopper.Context, value T) error,
+) error {
+	sub := source.Subscribe(ctx, notify.SubscribeOptions{
+		Buffer:   buffer,
+		Overflow: notify.OverflowFail,
+	})
+	defer sub.Close()
+	for {
+		select {
+		case value, ok := <-sub.C:
+			if !ok {
+				if err := sub.Err(); errors.Is(err, notify.ErrOverflow) {
+					return fmt.Errorf("after %d buffered values: %w", buffer, err)
+				}
+				return nil
+			}
+			if err := fn(ctx, value); err != nil {
+				return fmt.Errorf("value %v: %w", value, err)
+			}
+		case <-ctx.Stopping():
+			return nil
+		}
+	}
+}
+
+// DoWhenSnapshotChanged executes the callback when a snapshot, derived
+// from any number of variables, has changed to a different value. The
+// build function is called to construct a new snapshot once the
+// variables have changed and have then remained unchanged for the quiet
+// period. A burst of changes is therefore coalesced into a single
+// rebuild. A quiet period of zero rebuilds the snapshot as soon as any
+// variable changes, coalescing only those changes which occur before
+// the snapshot is rebuilt. If an error is returned from the callback,
+// the last successfully-processed snapshot will be returned. This
+// function returns once all variables have been closed and the final
+// snapshot has been processed.
+func DoWhenSnapshotChanged[S comparable](
+	ctx *stopper.Context,
+	build func() S,
+	quiet time.Duration,
+	fn func(ctx *stopper.Context, old, new S) error,
+	vars ...notify.UntypedVar,
+) (last S, err error) {
+	agg := notify.NewAggregation()
+	agg.Add(vars...)
+
+	// Re-arm all changed variables before building the snapshot so that
+	// a subsequent change is not missed. Closed variables will not be
+	// re-armed.
+	rearm := func() {
+		for {
+			v, ok := agg.Choose()
+			if !ok {
+				return
+			}
+			agg.Add(v)
+		}
+	}
+
+	var timer *time.Timer
+	if quiet > 0 {
+		timer = time.NewTimer(quiet)
+		timer.Stop()
+		defer timer.Stop()
+	}
+
+	last = build()
+	for {
+		// All variables have been closed.
+		if agg.Len() == 0 {
+			return last, nil
+		}
+		select {
+		case <-agg.Updated(ctx):
+		case <-ctx.Stopping():
+		}
 		if ctx.IsStopping() {
 			return last, nil
 		}
+		rearm()
+
+		// Wait for the variables to settle. The final snapshot is built
+		// without delay once every variable has been closed.
+		if timer != nil {
+			timer.Reset(quiet)
+		settle:
+			for agg.Len() > 0 {
+				select {
+				case <-agg.Updated(ctx):
+					rearm()
+					timer.Reset(quiet)
+				case <-timer.C:
+					break settle
+				case <-ctx.Stopping():
+					return last, nil
+				}
+			}
+		}
+
+		next := build()
+		if next == last {
+			continue
+		}
 		if err := fn(ctx, last, next); err != nil {
 			return last, fmt.Errorf("changed [%v -> %v]: %w", last, next, err)
 		}
@@ -63,15 +372,169 @@ func DoWhenChangedOrInterval[T comparable](
 	}
 }
 
+// DoWhenValue executes the enter callback each time the variable
+// transitions to the target value, including when the variable
+// initially contains the target value. The optional leave callback is
+// executed each time the variable transitions away from the target
+// value. Transitions that occur in rapid succession may be coalesced,
+// so that neither callback is executed. If an error is returned from
+// either callback, this function will return it. Otherwise, this
+// function returns once the source has been closed.
+func DoWhenValue[T comparable](
+	ctx *stopper.Context,
+	target T,
+	source *notify.Var[T],
+	enter, leave func(ctx *stopper.Context) error,
+) error {
+	in := false
+	for {
+		value, _, changed, closed := varstate.Load[T](source)
+		if now := value == target; now != in {
+			in = now
+			if now {
+				if err := enter(ctx); err != nil {
+					return fmt.Errorf("entering %v: %w", target, err)
+				}
+			} else if leave != nil {
+				if err := leave(ctx); err != nil {
+					return fmt.Errorf("leaving %v: %w", target, err)
+				}
+			}
+		}
+		if closed {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Stopping():
+			return nil
+		}
+	}
+}
+
+// Every executes the callback with the most recent value of the
+// variable each time the period elapses, regardless of whether the
+// variable has changed. The first invocation occurs once the period
+// has elapsed. Unlike [DoWhenChangedOrInterval], changes to the
+// variable do not trigger the callback. If an error is returned from
+// the callback, this function will return it. Otherwise, this function
+// returns once the context is stopping or the source has been closed.
+func Every[T any](
+	ctx *stopper.Context,
+	period time.Duration,
+	source *notify.Var[T],
+	fn func(ctx *stopper.Context, value T) error,
+) error {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Stopping():
+			return nil
+		}
+		value, _, _, closed := varstate.Load[T](source)
+		if closed {
+			return nil
+		}
+		if err := fn(ctx, value); err != nil {
+			return fmt.Errorf("every %s: %w", period, err)
+		}
+	}
+}
+
+// ServeAggregation starts a stopper task that invokes the handler for
+// each variable in the Aggregation as it changes. Each variable is
+// re-armed before the handler is invoked, so that the handler need not
+// re-register it; closed variables are dispatched once and then
+// removed. The task exits once the context begins stopping. If the
+// handler returns an error, the task will exit and the error will be
+// reported by the stopper.
+func ServeAggregation(
+	ctx *stopper.Context,
+	agg *notify.Aggregation,
+	fn func(ctx *stopper.Context, v notify.UntypedVar) error,
+) {
+	ctx.Go(func(ctx *stopper.Context) error {
+		waitCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-ctx.Stopping():
+				cancel()
+			case <-waitCtx.Done():
+			}
+		}()
+
+		for v := range agg.Changed(waitCtx) {
+			if err := fn(ctx, v); err != nil {
+				return fmt.Errorf("dispatch: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// VerifyAgainst periodically compares the source with a value fetched
+// from a remote source of truth. This is useful for detecting updates
+// that were missed by an unreliable push mechanism. The returned
+// variable will be updated with the outcome of every comparison. A
+// remote value that matches the local value from either before or after
+// the fetch is not considered to be divergent, since the source may
+// have been updated while the fetch was in flight.
+func VerifyAgainst[T comparable](
+	ctx *stopper.Context,
+	source *notify.Var[T],
+	fetch func(ctx *stopper.Context) (T, error),
+	interval time.Duration,
+) *notify.Var[Divergence[T]] {
+	ret := &notify.Var[Divergence[T]]{}
+	ctx.Go(func(ctx *stopper.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			before := source.Load()
+			remote, err := fetch(ctx)
+			local := source.Load()
+			ret.Set(Divergence[T]{
+				Checked:  time.Now(),
+				Diverged: err == nil && remote != before && remote != local,
+				Err:      err,
+				Local:    local,
+				Remote:   remote,
+			})
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Stopping():
+				return nil
+			}
+		}
+	})
+	return ret
+}
+
 // WaitForChange is a utility function that waits for the source to
-// change to another value. If the context is stopped, the most recent
-// value will be returned.
+// change to another value. If the context is stopped or the source is
+// closed, the most recent value will be returned.
 func WaitForChange[T comparable](
 	ctx *stopper.Context, current T, source *notify.Var[T],
+) (next T, changed <-chan struct{}) {
+	return WaitForChangeFunc(ctx, current, source, func(a, b T) bool { return a == b })
+}
+
+// WaitForChangeFunc is a utility function that waits for the source to
+// change to a value which is not equal to the current value, as
+// determined by the comparator. This allows non-comparable types to be
+// used. If the context is stopped or the source is closed, the most
+// recent value will be returned.
+func WaitForChangeFunc[T any](
+	ctx *stopper.Context, current T, source *notify.Var[T], eq func(a, b T) bool,
 ) (next T, changed <-chan struct{}) {
 	for {
-		next, changed = source.Get()
-		if current != next {
+		var closed bool
+		next, _, changed, closed = varstate.Load[T](source)
+		if !eq(current, next) || closed {
 			return next, changed
 		}
 		select {
@@ -83,38 +546,139 @@ func WaitForChange[T comparable](
 	}
 }
 
+// WaitForChangeTimeout is a utility function that waits for the source
+// to change to another value. If the timeout elapses first, a
+// [TimeoutError] will be returned. If the context is stopped or the
+// source is closed, the most recent value will be returned.
+func WaitForChangeTimeout[T comparable](
+	ctx *stopper.Context, current T, source *notify.Var[T], timeout time.Duration,
+) (next T, changed <-chan struct{}, err error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		var closed bool
+		next, _, changed, closed = varstate.Load[T](source)
+		if current != next || closed {
+			return next, changed, nil
+		}
+		select {
+		case <-changed:
+			continue
+		case <-timer.C:
+			return current, changed, &TimeoutError[T]{Last: current, Timeout: timeout}
+		case <-ctx.Stopping():
+			return current, changed, nil
+		}
+	}
+}
+
+// WaitForChangeOrDeadline is a utility function that waits for the
+// source to change to another value or for the deadline to pass. If the
+// deadline has already passed, this function returns immediately. If
+// the source is closed, the most recent value will be returned.
+func WaitForChangeOrDeadline[T comparable](
+	ctx *stopper.Context, current T, source *notify.Var[T], deadline time.Time,
+) (next T, changed <-chan struct{}) {
+	next, _, changed, closed := varstate.Load[T](source)
+	if current != next || closed {
+		return next, changed
+	}
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return current, changed
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-changed:
+		case <-timer.C:
+			return current, changed
+		case <-ctx.Stopping():
+			return current, changed
+		}
+		next, _, changed, closed = varstate.Load[T](source)
+		if current != next || closed {
+			return next, changed
+		}
+	}
+}
+
 // WaitForChangeOrDuration is a utility function that waits for the
 // source to change to another value or for the given duration to
-// elapse.
+// elapse. A non-positive duration causes this function to return
+// immediately. If the source is closed, the most recent value will be
+// returned.
 func WaitForChangeOrDuration[T comparable](
 	ctx *stopper.Context, current T, source *notify.Var[T], d time.Duration,
 ) (next T, changed <-chan struct{}) {
-	timer := time.NewTimer(d)
-	defer timer.Stop()
+	return WaitForChangeOrDeadline(ctx, current, source, time.Now().Add(d))
+}
+
+// WaitForFunc is a utility function that waits until the value of the
+// source satisfies the predicate. The matching value will be returned.
+// An error wrapping [notify.ErrClosed] will be returned if the source
+// is closed before a matching value is observed. This is useful for
+// tests and readiness checks that cannot be expressed as equality.
+func WaitForFunc[T any](ctx *stopper.Context, source *notify.Var[T], pred func(T) bool) (T, error) {
 	for {
-		next, changed = source.Get()
-		if current != next {
-			return next, changed
+		found, _, changed, closed := varstate.Load[T](source)
+		if pred(found) {
+			return found, nil
+		}
+		if closed {
+			return found, fmt.Errorf("%w, last saw %v", notify.ErrClosed, found)
 		}
 		select {
 		case <-changed:
 			continue
+		case <-ctx.Stopping():
+			return found, fmt.Errorf("context is stopping, last saw %v", found)
+		case <-ctx.Done():
+			return found, ctx.Err()
+		}
+	}
+}
+
+// WaitForStable is a utility function that waits until the source has
+// not changed for the given window of time and then returns its value.
+// A closed source is considered to be stable, since it will never
+// change again. This is useful for waiting until a variable has stopped
+// flapping.
+func WaitForStable[T any](ctx *stopper.Context, source *notify.Var[T], window time.Duration) (T, error) {
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	for {
+		found, _, changed, closed := varstate.Load[T](source)
+		if closed {
+			return found, nil
+		}
+		select {
+		case <-changed:
+			timer.Reset(window)
 		case <-timer.C:
-			return current, changed
+			return found, nil
 		case <-ctx.Stopping():
-			return current, changed
+			return found, fmt.Errorf("context is stopping, last saw %v", found)
+		case <-ctx.Done():
+			return found, ctx.Err()
 		}
 	}
 }
 
 // WaitForValue is a utility function that waits until the source emits
-// the requested value. This is primarily intended for testing.
+// the requested value. An error wrapping [notify.ErrClosed] will be
+// returned if the source is closed before the value is observed. This
+// is primarily intended for testing.
 func WaitForValue[T comparable](ctx *stopper.Context, expected T, source *notify.Var[T]) error {
 	for {
-		found, changed := source.Get()
+		found, _, changed, closed := varstate.Load[T](source)
 		if found == expected {
 			return nil
 		}
+		if closed {
+			return fmt.Errorf("%w, last saw %v while expecting %v", notify.ErrClosed, found, expected)
+		}
 		select {
 		case <-changed:
 			continue
@@ -125,3 +689,104 @@ func WaitForValue[T comparable](ctx *stopper.Context, expected T, source *notify
 		}
 	}
 }
+
+// WaitForValueTimeout is a utility function that waits until the source
+// emits the requested value. If the timeout elapses first, a
+// [TimeoutError] containing the last-observed value will be returned.
+// This is useful for tests and health checks that should report how
+// close the system came to the expected state.
+func WaitForValueTimeout[T comparable](
+	ctx *stopper.Context, expected T, source *notify.Var[T], timeout time.Duration,
+) (last T, err error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		found, _, changed, closed := varstate.Load[T](source)
+		if found == expected {
+			return found, nil
+		}
+		if closed {
+			return found, fmt.Errorf("%w, last saw %v while expecting %v", notify.ErrClosed, found, expected)
+		}
+		select {
+		case <-changed:
+			continue
+		case <-timer.C:
+			return found, &TimeoutError[T]{Last: found, Timeout: timeout}
+		case <-ctx.Stopping():
+			return found, fmt.Errorf("context is stopping, last saw %v while expecting %v", found, expected)
+		case <-ctx.Done():
+			return found, ctx.Err()
+		}
+	}
+}
+
+// WaitForValues is a utility function that waits until all conditions
+// are simultaneously satisfied. That is, no variable will have changed
+// between the evaluation of the first and last conditions. This is
+// primarily intended for testing.
+func WaitForValues(ctx *stopper.Context, conditions ...Condition) error {
+	changed := make([]<-chan struct{}, len(conditions))
+outer:
+	for {
+		for idx, cond := range conditions {
+			ch, err := cond.check()
+			if err == nil {
+				changed[idx] = ch
+				continue
+			}
+			if errors.Is(err, notify.ErrClosed) {
+				return fmt.Errorf("condition %d: %w", idx, err)
+			}
+			// No progress can be made until this variable changes.
+			select {
+			case <-ch:
+				continue outer
+			case <-ctx.Stopping():
+				return fmt.Errorf("context is stopping, condition %d: %w", idx, err)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		// Ensure that the conditions were satisfied simultaneously.
+		for _, ch := range changed {
+			select {
+			case <-ch:
+				continue outer
+			default:
+			}
+		}
+		return nil
+	}
+}
+
+// supersede returns a context that will be canceled with
+// [ErrSuperseded] once the source changes to a value other than the
+// current value. The changed channel must correspond to the current
+// value. The returned function must be called to release the context.
+func supersede[T comparable](
+	ctx context.Context, current T, changed <-chan struct{}, source *notify.Var[T],
+) (context.Context, context.CancelFunc) {
+	superseded, cancel := context.WithCancelCause(ctx)
+	go func() {
+		for {
+			select {
+			case <-changed:
+			case <-superseded.Done():
+				return
+			}
+			var next T
+			var closed bool
+			next, _, changed, closed = varstate.Load[T](source)
+			if next != current {
+				cancel(ErrSuperseded)
+				return
+			}
+			if closed {
+				return
+			}
+		}
+	}()
+	return superseded, func() { cancel(nil) }
+}
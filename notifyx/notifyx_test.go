@@ -6,6 +6,9 @@ package notifyx
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -15,6 +18,67 @@ import (
 	"vawter.tech/stopper"
 )
 
+func TestApply(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf(1)
+
+	// Successful application retains the new value.
+	r.NoError(Apply(stop, v, 2, func(_ *stopper.Context, value int) error {
+		r.Equal(2, value)
+		found, _ := v.Get()
+		r.Equal(2, found)
+		return nil
+	}))
+	found, _ := v.Get()
+	r.Equal(2, found)
+
+	// A failed application restores the previous value.
+	_, ch := v.Get()
+	err := Apply(stop, v, 3, func(*stopper.Context, int) error {
+		return errors.New("expected")
+	})
+	r.ErrorContains(err, "expected")
+	found, _ = v.Get()
+	r.Equal(2, found)
+	select {
+	case <-ch:
+	default:
+		r.Fail("channel should be closed")
+	}
+
+	// A concurrent change should not be clobbered by the rollback.
+	err = Apply(stop, v, 4, func(*stopper.Context, int) error {
+		v.Set(5)
+		return errors.New("expected")
+	})
+	r.ErrorContains(err, "expected")
+	found, _ = v.Get()
+	r.Equal(5, found)
+
+	// A concurrent change that restores the applied value should not be
+	// rolled back either.
+	err = Apply(stop, v, 6, func(*stopper.Context, int) error {
+		v.Set(7)
+		v.Set(6)
+		return errors.New("expected")
+	})
+	r.ErrorContains(err, "expected")
+	found, _ = v.Get()
+	r.Equal(6, found)
+
+	// A value that cannot be stored is not applied.
+	v.Close()
+	err = Apply(stop, v, 8, func(*stopper.Context, int) error {
+		r.Fail("should not be called")
+		return nil
+	})
+	r.ErrorIs(err, notify.ErrClosed)
+}
+
 func TestDoWhenChanged(t *testing.T) {
 	r := require.New(t)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
@@ -53,6 +117,65 @@ func TestDoWhenChanged(t *testing.T) {
 	r.True(called.Load())
 }
 
+func TestDoWhenChangedDebounced(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf(0)
+	var calls []int
+	var last int
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		last, err = DoWhenChangedDebounced(stop, 0, v, 50*time.Millisecond,
+			func(_ *stopper.Context, _, next int) error {
+				calls = append(calls, next)
+				return nil
+			})
+	}()
+
+	// A burst of changes is coalesced.
+	for i := 1; i <= 10; i++ {
+		v.Set(i)
+	}
+	v.Close()
+	<-done
+	r.NoError(err)
+	r.Equal(10, last)
+	r.Equal([]int{10}, calls)
+}
+
+func TestDoWhenChangedFunc(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := notify.VarOf([]string{"a"})
+	seen := make(chan []string)
+
+	stop := stopper.WithContext(ctx)
+	stop.Go(func(stop *stopper.Context) error {
+		_, err := DoWhenChangedFunc(stop, []string{"a"}, v, slices.Equal,
+			func(_ *stopper.Context, _, next []string) error {
+				seen <- next
+				return nil
+			})
+		return err
+	})
+
+	// An equivalent slice is not a change.
+	v.Set([]string{"a"})
+	v.Set([]string{"a", "b"})
+	r.Equal([]string{"a", "b"}, <-seen)
+
+	v.Close()
+	stop.Stop(time.Minute)
+	r.NoError(stop.Wait())
+}
+
 func TestDoWhenChangedOrInterval(t *testing.T) {
 	r := require.New(t)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
@@ -99,6 +222,337 @@ func TestDoWhenChangedOrInterval(t *testing.T) {
 	r.True(called.Load())
 }
 
+func TestDoWhenChangedLatest(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	type call struct {
+		old, new int
+	}
+
+	v := notify.VarOf(0)
+	calls := make(chan call)
+	var last int
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		last, err = DoWhenChangedLatest(stop, 0, v,
+			func(_ *stopper.Context, superseded context.Context, old, new int) error {
+				calls <- call{old, new}
+				if new != 1 {
+					return nil
+				}
+				// Stale work is abandoned once a newer value is available.
+				<-superseded.Done()
+				return context.Cause(superseded)
+			})
+	}()
+
+	v.Set(1)
+	r.Equal(call{0, 1}, <-calls)
+	v.Set(2)
+	// The callback is executed again with the same old value.
+	r.Equal(call{0, 2}, <-calls)
+
+	v.Close()
+	<-done
+	r.NoError(err)
+	r.Equal(2, last)
+
+	// Other errors are returned, even if the value has changed.
+	v = notify.VarOf(0)
+	v.Set(1)
+	_, err = DoWhenChangedLatest(stop, 0, v,
+		func(_ *stopper.Context, superseded context.Context, _, _ int) error {
+			v.Set(2)
+			<-superseded.Done()
+			return errors.New("boom")
+		})
+	r.ErrorContains(err, "changed [0 -> 1]: boom")
+}
+
+// TestDoWhenChangedStopper ensures that callbacks receive the caller's
+// stopper, so that they may stop the loop or start tasks which outlive
+// the callback.
+func TestDoWhenChangedStopper(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	loops := map[string]func(*stopper.Context, *notify.Var[int], func(*stopper.Context) error) error{
+		"async": func(ctx *stopper.Context, v *notify.Var[int], fn func(*stopper.Context) error) error {
+			_, err := DoWhenChangedAsync(ctx, 0, v, 1, BusyQueueLatest,
+				func(ctx *stopper.Context, _ context.Context, _, _ int) error {
+					return fn(ctx)
+				})
+			return err
+		},
+		"changed": func(ctx *stopper.Context, v *notify.Var[int], fn func(*stopper.Context) error) error {
+			_, err := DoWhenChanged(ctx, 0, v, func(ctx *stopper.Context, _, _ int) error {
+				return fn(ctx)
+			})
+			return err
+		},
+		"latest": func(ctx *stopper.Context, v *notify.Var[int], fn func(*stopper.Context) error) error {
+			_, err := DoWhenChangedLatest(ctx, 0, v,
+				func(ctx *stopper.Context, _ context.Context, _, _ int) error {
+					return fn(ctx)
+				})
+			return err
+		},
+	}
+
+	for name, loop := range loops {
+		t.Run(name+" stop", func(t *testing.T) {
+			r := require.New(t)
+			stop := stopper.WithContext(ctx)
+			v := notify.VarOf(0)
+			stop.Go(func(stop *stopper.Context) error {
+				return loop(stop, v, func(ctx *stopper.Context) error {
+					ctx.Stop(time.Minute)
+					return nil
+				})
+			})
+			v.Set(1)
+			// The source is never closed, so the loop must exit because
+			// the callback stopped it.
+			r.NoError(stop.Wait())
+		})
+
+		t.Run(name+" go", func(t *testing.T) {
+			r := require.New(t)
+			stop := stopper.WithContext(ctx)
+			v := notify.VarOf(0)
+			returned := make(chan struct{})
+			var taskErr error
+			stop.Go(func(stop *stopper.Context) error {
+				return loop(stop, v, func(ctx *stopper.Context) error {
+					ctx.Go(func(ctx *stopper.Context) error {
+						<-returned
+						<-ctx.Stopping()
+						taskErr = ctx.Err()
+						return nil
+					})
+					close(returned)
+					return nil
+				})
+			})
+			v.Set(1)
+			<-returned
+			// Give a broken implementation the chance to cancel the task.
+			time.Sleep(10 * time.Millisecond)
+			stop.Stop(time.Minute)
+			r.NoError(stop.Wait())
+			// The task was not canceled when the callback returned.
+			r.NoError(taskErr)
+		})
+	}
+}
+
+func TestDoWhenEach(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	t.Run("every value", func(t *testing.T) {
+		v := notify.VarOf(0)
+		var seen []int
+		subscribed := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- DoWhenEach(stop, v, 16, func(_ *stopper.Context, value int) error {
+				if seen = append(seen, value); len(seen) == 1 {
+					close(subscribed)
+				}
+				return nil
+			})
+		}()
+		<-subscribed
+		// These values would be coalesced by DoWhenChanged.
+		for i := 1; i <= 10; i++ {
+			v.Set(i)
+		}
+		v.Close()
+		r.NoError(<-done)
+		r.Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, seen)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		v := notify.VarOf(0)
+		release := make(chan struct{})
+		entered := make(chan struct{})
+		calls := 0
+		done := make(chan error)
+		go func() {
+			done <- DoWhenEach(stop, v, 2, func(*stopper.Context, int) error {
+				if calls++; calls == 1 {
+					close(entered)
+					<-release
+				}
+				return nil
+			})
+		}()
+		<-entered
+		// The subscription's channel has one slot in addition to the
+		// buffer, which was used by the initial value.
+		for i := 1; i <= 4; i++ {
+			v.Set(i)
+		}
+		close(release)
+		r.ErrorIs(<-done, notify.ErrOverflow)
+		// The initial value and the buffered values were processed.
+		r.Equal(4, calls)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		v := notify.VarOf(0)
+		err := DoWhenEach(stop, v, 0, func(*stopper.Context, int) error {
+			return errors.New("boom")
+		})
+		r.ErrorContains(err, "value 0: boom")
+	})
+}
+
+func TestDoWhenValue(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf("ready")
+	events := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- DoWhenValue(stop, "ready", v,
+			func(*stopper.Context) error {
+				events <- "enter"
+				return nil
+			},
+			func(*stopper.Context) error {
+				events <- "leave"
+				return nil
+			})
+	}()
+
+	// The initial value is a transition.
+	r.Equal("enter", <-events)
+	v.Set("busy")
+	r.Equal("leave", <-events)
+	v.Set("busy again")
+	v.Set("ready")
+	r.Equal("enter", <-events)
+
+	v.Close()
+	r.NoError(<-errs)
+
+	// Errors are returned.
+	err := DoWhenValue(stop, "ready", v, func(*stopper.Context) error {
+		return errors.New("boom")
+	}, nil)
+	r.ErrorContains(err, "entering ready: boom")
+}
+
+func TestEvery(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf(0)
+	seen := make(chan int)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- Every(stop, time.Millisecond, v, func(ctx *stopper.Context, value int) error {
+			select {
+			case seen <- value:
+			case <-ctx.Stopping():
+			}
+			return nil
+		})
+	}()
+
+	// The callback is invoked even though nothing has changed.
+	r.Equal(0, <-seen)
+	r.Equal(0, <-seen)
+	v.Set(1)
+	for value := range seen {
+		if value == 1 {
+			break
+		}
+	}
+
+	stop.Stop(time.Minute)
+	r.NoError(<-errs)
+
+	// Errors are returned.
+	err := Every(stopper.WithContext(ctx), time.Millisecond, v,
+		func(*stopper.Context, int) error {
+			return errors.New("boom")
+		})
+	r.ErrorContains(err, "every 1ms: boom")
+}
+
+func TestWaitForFunc(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := notify.VarOf([]string{"a"})
+	found := make(chan []string, 1)
+
+	stop := stopper.WithContext(ctx)
+	stop.Go(func(stop *stopper.Context) error {
+		next, err := WaitForFunc(stop, v, func(s []string) bool { return len(s) > 2 })
+		if err != nil {
+			return err
+		}
+		found <- next
+		return nil
+	})
+
+	v.Set([]string{"a", "b"})
+	v.Set([]string{"a", "b", "c"})
+	r.Equal([]string{"a", "b", "c"}, <-found)
+	stop.Stop(time.Minute)
+	r.NoError(stop.Wait())
+
+	// A closed source reports the last value.
+	v.Close()
+	last, err := WaitForFunc(stop, v, func(s []string) bool { return len(s) == 0 })
+	r.ErrorIs(err, notify.ErrClosed)
+	r.Len(last, 3)
+}
+
+func TestWaitForStable(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf(0)
+	go func() {
+		for i := 1; i <= 10; i++ {
+			v.Set(i)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	found, err := WaitForStable(stop, v, 100*time.Millisecond)
+	r.NoError(err)
+	r.Equal(10, found)
+	r.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+
+	// A closed source is stable.
+	v.Close()
+	found, err = WaitForStable(stop, v, time.Hour)
+	r.NoError(err)
+	r.Equal(10, found)
+}
+
 func TestWaitForValue(t *testing.T) {
 	r := require.New(t)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
@@ -122,3 +576,274 @@ func TestWaitForValue(t *testing.T) {
 	r.True(called.Load())
 
 }
+
+func TestDoWhenSnapshotChanged(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	type snapshot struct {
+		A int
+		B string
+	}
+	a := notify.VarOf(1)
+	b := notify.VarOf("x")
+	built := make(chan struct{})
+	var once sync.Once
+	build := func() snapshot {
+		once.Do(func() { close(built) })
+		return snapshot{a.Load(), b.Load()}
+	}
+
+	type call struct{ old, new snapshot }
+	var calls []call
+	stop := stopper.WithContext(ctx)
+	done := make(chan struct{})
+	var last snapshot
+	stop.Go(func(stop *stopper.Context) error {
+		defer close(done)
+		var err error
+		// The quiet period will not elapse, so the changes can only be
+		// delivered once the variables have been closed.
+		last, err = DoWhenSnapshotChanged(stop, build, time.Hour,
+			func(ctx *stopper.Context, old, new snapshot) error {
+				calls = append(calls, call{old, new})
+				return nil
+			}, a, b)
+		return err
+	})
+
+	<-built
+	a.Set(1) // No change to the snapshot.
+	a.Set(2)
+	b.Set("y")
+	a.Close()
+	b.Close()
+	<-done
+	stop.Stop(time.Minute) // The variables have been closed.
+	r.NoError(stop.Wait())
+	r.Equal([]call{{snapshot{1, "x"}, snapshot{2, "y"}}}, calls)
+	r.Equal(snapshot{2, "y"}, last)
+}
+
+func TestDoWhenSnapshotChangedQuiet(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	a := notify.VarOf(1)
+	b := notify.VarOf(10)
+	built := make(chan struct{})
+	var once sync.Once
+	build := func() int {
+		once.Do(func() { close(built) })
+		return a.Load() + b.Load()
+	}
+
+	var calls atomic.Int32
+	stop := stopper.WithContext(ctx)
+	stop.Go(func(stop *stopper.Context) error {
+		_, err := DoWhenSnapshotChanged(stop, build, 10*time.Millisecond,
+			func(ctx *stopper.Context, old, new int) error {
+				calls.Add(1)
+				if new == 22 {
+					stop.Stop(time.Minute)
+				}
+				return nil
+			}, a, b)
+		return err
+	})
+
+	<-built
+	a.Set(2)
+	b.Set(20)
+	r.NoError(stop.Wait())
+	r.GreaterOrEqual(calls.Load(), int32(1))
+}
+
+func TestServeAggregation(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	a := notify.VarOf(0)
+	b := notify.VarOf(0)
+	agg := notify.NewAggregation()
+	notify.Aggregate(agg, a)
+	notify.Aggregate(agg, b)
+
+	var sum atomic.Int64
+	ServeAggregation(stop, agg, func(_ *stopper.Context, v notify.UntypedVar) error {
+		value, ok := notify.ValueOf[int](v)
+		if !ok || value < 0 {
+			return errors.New("negative")
+		}
+		sum.Add(int64(value))
+		return nil
+	})
+
+	// The variables are re-armed after each dispatch.
+	for i := 1; i <= 3; i++ {
+		a.Set(i)
+		r.Eventually(func() bool {
+			return sum.Load() == int64(i*(i+1)/2)
+		}, time.Minute, time.Millisecond)
+	}
+	r.Equal(2, agg.Len())
+
+	// A handler error stops the task.
+	b.Set(-1)
+	r.ErrorContains(stop.Wait(), "negative")
+}
+
+func TestVerifyAgainst(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf(1)
+	var remote atomic.Int64
+	remote.Store(1)
+	report := VerifyAgainst(stop, v, func(*stopper.Context) (int, error) {
+		return int(remote.Load()), nil
+	}, time.Millisecond)
+
+	r.Eventually(func() bool {
+		d := report.Load()
+		return !d.Checked.IsZero() && !d.Diverged
+	}, time.Minute, time.Millisecond)
+
+	remote.Store(2)
+	r.Eventually(func() bool {
+		d := report.Load()
+		return d.Diverged && d.Local == 1 && d.Remote == 2
+	}, time.Minute, time.Millisecond)
+
+	v.Set(2)
+	r.Eventually(func() bool {
+		d := report.Load()
+		return !d.Diverged && d.Local == 2
+	}, time.Minute, time.Millisecond)
+
+	stop.Stop(time.Minute)
+	r.NoError(stop.Wait())
+}
+
+func TestWaitForChangeFunc(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf([]int{1})
+	go func() {
+		v.Set([]int{1})
+		v.Set([]int{1, 2})
+	}()
+
+	next, _ := WaitForChangeFunc(stop, []int{1}, v, slices.Equal[[]int])
+	r.Equal([]int{1, 2}, next)
+}
+
+func TestWaitForValues(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	a := notify.VarOf(0)
+	b := notify.VarOf("")
+	go func() {
+		for i := range 10 {
+			a.Set(i)
+		}
+		b.Set("ready")
+	}()
+
+	r.NoError(WaitForValues(stop,
+		ValueIs(a, 9),
+		ValueMatches(b, func(s string) bool { return len(s) > 0 }),
+	))
+
+	// Verify that an unsatisfied condition is interrupted.
+	stop.Stop(time.Minute)
+	r.Error(WaitForValues(stop, ValueIs(a, -1)))
+	r.NoError(stop.Wait())
+}
+
+func TestWaitForTimeouts(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf(1)
+
+	last, err := WaitForValueTimeout(stop, 2, v, time.Millisecond)
+	r.Equal(1, last)
+	var timeout *TimeoutError[int]
+	r.ErrorAs(err, &timeout)
+	r.Equal(1, timeout.Last)
+	r.Equal(time.Millisecond, timeout.Timeout)
+
+	next, _, err := WaitForChangeTimeout(stop, 1, v, time.Millisecond)
+	r.Equal(1, next)
+	r.ErrorAs(err, &timeout)
+
+	v.Set(2)
+	last, err = WaitForValueTimeout(stop, 2, v, time.Minute)
+	r.NoError(err)
+	r.Equal(2, last)
+
+	next, _, err = WaitForChangeTimeout(stop, 1, v, time.Minute)
+	r.NoError(err)
+	r.Equal(2, next)
+	// An expired deadline returns immediately.
+	next, _ = WaitForChangeOrDeadline(stop, 2, v, time.Now().Add(-time.Hour))
+	r.Equal(2, next)
+	next, _ = WaitForChangeOrDuration(stop, 2, v, 0)
+	r.Equal(2, next)
+
+	go v.Set(3)
+	next, _ = WaitForChangeOrDeadline(stop, 2, v, time.Now().Add(time.Minute))
+	r.Equal(3, next)
+}
+
+func TestClosedSource(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	v := notify.VarOf(1)
+	var last atomic.Int64
+	exited := make(chan struct{})
+	stop.Go(func(stop *stopper.Context) error {
+		defer close(exited)
+		_, err := DoWhenChanged(stop, 1, v, func(_ *stopper.Context, _, new int) error {
+			last.Store(int64(new))
+			return nil
+		})
+		return err
+	})
+
+	// The final value should be delivered before the loop exits.
+	v.Set(2)
+	v.Close()
+	select {
+	case <-exited:
+	case <-ctx.Done():
+		r.NoError(ctx.Err())
+	}
+	r.Equal(int64(2), last.Load())
+
+	r.ErrorIs(WaitForValue(stop, 3, v), notify.ErrClosed)
+	r.ErrorIs(WaitForValues(stop, ValueIs(v, 3)), notify.ErrClosed)
+	next, _ := WaitForChange(stop, 2, v)
+	r.Equal(2, next)
+
+	stop.Stop(time.Minute)
+	r.NoError(stop.Wait())
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"vawter.tech/notify"
+	"vawter.tech/stopper"
+)
+
+// Backoff describes an exponential-backoff policy that is used by
+// [DoWhenChangedRetry]. The zero value retries indefinitely, with a
+// delay that doubles from 100ms.
+type Backoff struct {
+	Initial     time.Duration // The delay before the first retry; defaults to 100ms.
+	Max         time.Duration // The maximum delay between retries, if non-zero.
+	MaxAttempts int           // The number of attempts per change, if non-zero.
+	Multiplier  float64       // Applied to the delay after each retry; defaults to 2.
+}
+
+// DoWhenChangedRetry is analogous to [DoWhenChanged], except that a
+// callback which returns an error will be retried, with the same old
+// and new values, according to the backoff policy. The loop will exit
+// only if the callback returns an error that has been marked with
+// [Permanent] or if the maximum number of attempts is exceeded. If the
+// context is stopped while a retry is pending, the last successfully
+// processed value will be returned without an error.
+func DoWhenChangedRetry[T comparable](
+	ctx *stopper.Context,
+	start T,
+	source *notify.Var[T],
+	backoff Backoff,
+	fn func(ctx *stopper.Context, old, new T) error,
+) (last T, err error) {
+	last, err = DoWhenChanged(ctx, start, source, func(ctx *stopper.Context, old, new T) error {
+		return retry(ctx, backoff, func() error { return fn(ctx, old, new) })
+	})
+	if errors.Is(err, errStopped) {
+		err = nil
+	}
+	return last, err
+}
+
+// defaultInitialBackoff is used when [Backoff.Initial] is not positive,
+// to avoid retrying in a hot loop.
+const defaultInitialBackoff = 100 * time.Millisecond
+
+// errStopped is returned from retry if the context is stopped while a
+// retry is pending.
+var errStopped = errors.New("stopped while retrying")
+
+// Permanent wraps the error to indicate to [DoWhenChangedRetry] that the
+// operation should not be retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// retry invokes the function until it succeeds, subject to the backoff
+// policy.
+func retry(ctx *stopper.Context, backoff Backoff, fn func() error) error {
+	multiplier := backoff.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := backoff.Initial
+	if delay <= 0 {
+		delay = defaultInitialBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return err
+		}
+		if backoff.MaxAttempts > 0 && attempt >= backoff.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Stopping():
+			timer.Stop()
+			return errStopped
+		}
+
+		delay = time.Duration(float64(delay) * multiplier)
+		if backoff.Max > 0 && delay > backoff.Max {
+			delay = backoff.Max
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+	"vawter.tech/stopper"
+)
+
+func TestDoWhenChangedRetry(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	backoff := Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond}
+
+	t.Run("transient", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		attempts := 0
+		stop.Go(func(stop *stopper.Context) error {
+			_, err := DoWhenChangedRetry(stop, 0, v, backoff,
+				func(_ *stopper.Context, old, new int) error {
+					r.Equal(0, old)
+					r.Equal(1, new)
+					if attempts++; attempts < 5 {
+						return errors.New("transient")
+					}
+					stop.Stop(time.Minute)
+					return nil
+				})
+			return err
+		})
+		v.Set(1)
+		r.NoError(stop.Wait())
+		r.Equal(5, attempts)
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		attempts := 0
+		v.Set(1)
+		_, err := DoWhenChangedRetry(stop, 0, v, backoff,
+			func(*stopper.Context, int, int) error {
+				attempts++
+				return Permanent(errors.New("permanent"))
+			})
+		r.ErrorContains(err, "permanent")
+		r.Equal(1, attempts)
+		r.Nil(Permanent(nil))
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		attempts := 0
+		v.Set(1)
+		limited := backoff
+		limited.MaxAttempts = 3
+		last, err := DoWhenChangedRetry(stop, 0, v, limited,
+			func(*stopper.Context, int, int) error {
+				attempts++
+				return errors.New("transient")
+			})
+		r.ErrorContains(err, "giving up after 3 attempts")
+		r.Equal(0, last)
+		r.Equal(3, attempts)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		v.Set(1)
+		last, err := DoWhenChangedRetry(stop, 0, v, Backoff{Initial: time.Hour},
+			func(*stopper.Context, int, int) error {
+				stop.Stop(time.Minute)
+				return errors.New("transient")
+			})
+		r.NoError(err)
+		r.Equal(0, last)
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		stop := stopper.WithContext(ctx)
+		v := notify.VarOf(0)
+		v.Set(1)
+		attempts := 0
+		time.AfterFunc(10*time.Millisecond, func() { stop.Stop(time.Minute) })
+		last, err := DoWhenChangedRetry(stop, 0, v, Backoff{},
+			func(*stopper.Context, int, int) error {
+				attempts++
+				return errors.New("transient")
+			})
+		r.NoError(err)
+		r.Equal(0, last)
+		// The retry is delayed, rather than executed immediately.
+		r.Equal(1, attempts)
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyx
+
+import (
+	"fmt"
+
+	"vawter.tech/notify"
+	"vawter.tech/notify/internal/varstate"
+	"vawter.tech/stopper"
+)
+
+// Reason describes why [DoWhenChangedOrSignal] invoked its callback.
+type Reason int
+
+const (
+	// ReasonChanged indicates that the variable changed to a different
+	// value.
+	ReasonChanged Reason = iota
+	// ReasonSignal indicates that the signal channel fired. The old and
+	// new values passed to the callback may be equal.
+	ReasonSignal
+)
+
+// String implements [fmt.Stringer].
+func (r Reason) String() string {
+	switch r {
+	case ReasonChanged:
+		return "changed"
+	case ReasonSignal:
+		return "signal"
+	default:
+		return fmt.Sprintf("Reason(%d)", int(r))
+	}
+}
+
+// DoWhenChangedOrSignal executes the callback when the variable has
+// changed to a different value or when a value is received from the
+// signal channel. This is useful for responding to a manual refresh
+// request or to an operating-system signal, such as SIGHUP, from the
+// same loop that processes changes. The callback always receives the
+// most recent value of the variable. If the signal channel is closed,
+// it will be ignored thereafter. If an error is returned from the
+// callback, the last successfully-processed value will be returned.
+// This function returns once the source has been closed.
+func DoWhenChangedOrSignal[T comparable, S any](
+	ctx *stopper.Context,
+	start T,
+	source *notify.Var[T],
+	signal <-chan S,
+	fn func(ctx *stopper.Context, reason Reason, old, new T) error,
+) (last T, err error) {
+	last = start
+	for {
+		next, _, changed, closed := varstate.Load[T](source)
+		reason := ReasonChanged
+		if next == last {
+			if closed {
+				return last, nil
+			}
+			select {
+			case <-changed:
+				continue
+			case _, ok := <-signal:
+				if !ok {
+					signal = nil
+					continue
+				}
+				reason = ReasonSignal
+				next = source.Load()
+			case <-ctx.Stopping():
+				return last, nil
+			}
+		}
+		if err := fn(ctx, reason, last, next); err != nil {
+			return last, fmt.Errorf("%s [%v -> %v]: %w", reason, last, next, err)
+		}
+		last = next
+	}
+}
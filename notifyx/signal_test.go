@@ -0,0 +1,66 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notifyx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"vawter.tech/notify"
+	"vawter.tech/stopper"
+)
+
+func TestDoWhenChangedOrSignal(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	type call struct {
+		reason   Reason
+		old, new int
+	}
+
+	v := notify.VarOf(0)
+	refresh := make(chan struct{})
+	calls := make(chan call)
+	var last int
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		last, err = DoWhenChangedOrSignal(stop, 0, v, refresh,
+			func(_ *stopper.Context, reason Reason, old, new int) error {
+				calls <- call{reason, old, new}
+				return nil
+			})
+	}()
+
+	v.Set(1)
+	r.Equal(call{ReasonChanged, 0, 1}, <-calls)
+	refresh <- struct{}{}
+	r.Equal(call{ReasonSignal, 1, 1}, <-calls)
+
+	// A closed signal channel is ignored.
+	close(refresh)
+	v.Set(2)
+	r.Equal(call{ReasonChanged, 1, 2}, <-calls)
+
+	v.Close()
+	<-done
+	r.NoError(err)
+	r.Equal(2, last)
+
+	// Errors are returned.
+	signal := make(chan struct{}, 1)
+	signal <- struct{}{}
+	_, err = DoWhenChangedOrSignal(stop, 0, notify.VarOf(0), signal,
+		func(*stopper.Context, Reason, int, int) error {
+			return errors.New("boom")
+		})
+	r.ErrorContains(err, "signal [0 -> 0]: boom")
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+// An Interceptor is invoked for each write to a Var. It receives the
+// current and proposed values, as well as a function that continues the
+// write. An Interceptor may observe the write, substitute a different
+// value, or veto the write by returning an error without calling
+// proceed. Any error will be returned to the writer in the same manner
+// as a validation error. Interceptors are invoked while the Var's write
+// lock is held and must not access the Var. See [WithInterceptor].
+type Interceptor[T any] func(old, next T, proceed func(next T) error) error
+
+// An Option customizes the behavior of a Var. Options are passed to
+// [VarOf].
+type Option[T any] func(v *Var[T])
+
+// ByPointer configures a Var that holds a pointer to suppress
+// notifications when the same pointer is stored again. Storing any
+// other pointer is treated as a change, without comparing the values
+// being pointed to. This matches the idiom of publishing immutable
+// snapshots and avoids the cost of comparing large structs.
+func ByPointer[P any]() Option[*P] {
+	return WithEqual(func(a, b *P) bool { return a == b })
+}
+
+// WithEqual configures the Var to use the comparator to detect no-op
+// writes. If the comparator reports that a newly-stored value is equal
+// to the current value, the current value will be retained and
+// listeners will not be notified. This allows variables containing
+// slices, maps, or other non-comparable types to suppress spurious
+// notifications.
+func WithEqual[T any](eq func(a, b T) bool) Option[T] {
+	return func(v *Var[T]) {
+		v.eq = eq
+	}
+}
+
+// WithInterceptor adds an [Interceptor] to the Var. This option may be
+// specified multiple times, in which case the first interceptor will be
+// the outermost. Interceptors run before any validator configured with
+// [WithValidator].
+func WithInterceptor[T any](fn Interceptor[T]) Option[T] {
+	return func(v *Var[T]) {
+		v.interceptors = append(v.interceptors, fn)
+	}
+}
+
+// WithValidator configures the Var to check every newly-stored value.
+// If the validator returns an error, the value will not be stored and
+// listeners will not be notified. The error will be returned from
+// [Var.TrySet] and [Var.Update]. This prevents an invalid configuration
+// from being published to every consumer. The initial value passed to
+// [VarOf] is not validated.
+func WithValidator[T any](validate func(T) error) Option[T] {
+	return func(v *Var[T]) {
+		v.validate = validate
+	}
+}
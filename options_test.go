@@ -0,0 +1,123 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestByPointer(t *testing.T) {
+	r := require.New(t)
+
+	type config struct{ Setting string }
+	first := &config{"a"}
+	v := VarOf(first, ByPointer[config]())
+	_, ch := v.Get()
+
+	// Re-storing the same pointer should not notify.
+	v.Set(first)
+	r.False(Changed(ch))
+
+	// An equal value at a different address is a change.
+	second := &config{"a"}
+	v.Set(second)
+	r.True(Changed(ch))
+	r.Same(second, v.Load())
+}
+
+func TestWithInterceptor(t *testing.T) {
+	r := require.New(t)
+
+	var audit []string
+	logger := func(old, next int, proceed func(int) error) error {
+		audit = append(audit, fmt.Sprintf("before %d -> %d", old, next))
+		err := proceed(next)
+		audit = append(audit, fmt.Sprintf("after %d -> %d: %v", old, next, err))
+		return err
+	}
+	clamp := func(_, next int, proceed func(int) error) error {
+		if next < 0 {
+			return errors.New("veto")
+		}
+		return proceed(min(next, 10))
+	}
+
+	v := VarOf(1, WithInterceptor(logger), WithInterceptor(clamp))
+	_, ch := v.Get()
+
+	_, err := v.TrySet(-1)
+	r.ErrorContains(err, "veto")
+	r.False(Changed(ch))
+
+	v.Set(100)
+	r.Equal(10, v.Load())
+	r.True(Changed(ch))
+
+	r.Equal([]string{
+		"before 1 -> -1",
+		"after 1 -> -1: veto",
+		"before 1 -> 100",
+		"after 1 -> 100: <nil>",
+	}, audit)
+}
+
+func TestWithEqual(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf([]string{"a"}, WithEqual(slices.Equal[[]string]))
+	_, ch := v.Get()
+
+	// Storing an equal value should not notify.
+	r.Equal(ch, v.Set([]string{"a"}))
+	_, _, err := v.Update(func(old []string) ([]string, error) {
+		return slices.Clone(old), nil
+	})
+	r.NoError(err)
+	select {
+	case <-ch:
+		r.Fail("channel should be open")
+	default:
+	}
+
+	// A different value should notify.
+	v.Set([]string{"a", "b"})
+	select {
+	case <-ch:
+	default:
+		r.Fail("channel should be closed")
+	}
+	r.Equal([]string{"a", "b"}, v.Load())
+}
+
+func TestWithValidator(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(1, WithValidator(func(value int) error {
+		if value < 0 {
+			return errors.New("negative")
+		}
+		return nil
+	}))
+	_, ch := v.Get()
+
+	// Rejected writes do not change the value or notify.
+	_, err := v.TrySet(-1)
+	r.ErrorContains(err, "negative")
+	v.Set(-1)
+	_, _, err = v.Update(func(int) (int, error) { return -1, nil })
+	r.ErrorContains(err, "negative")
+	r.False(CompareAndSwap(v, 1, -1))
+	r.False(Changed(ch))
+	r.Equal(1, v.Load())
+
+	_, err = v.TrySet(2)
+	r.NoError(err)
+	r.True(Changed(ch))
+	r.Equal(2, v.Load())
+}
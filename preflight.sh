@@ -21,4 +21,10 @@ step "go test" go test -v -race -coverpkg=./... -covermode=atomic -coverprofile=
 step "staticcheck" go run honnef.co/go/tools/cmd/staticcheck@latest -checks all ./...
 step "go vet" go vet ./...
 
+# The analyzer is a separate module, to keep its dependencies out of the
+# notify module.
+step "notifyvet test" go -C notifyvet test -v -race ./...
+step "notifyvet staticcheck" go -C notifyvet run honnef.co/go/tools/cmd/staticcheck@latest -checks all ./...
+step "notifyvet go vet" go -C notifyvet vet ./...
+
 exit $failed
\ No newline at end of file
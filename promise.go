@@ -0,0 +1,81 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// A Promise is a write-once variable. It may be resolved exactly once,
+// with either a value or an error. Any number of goroutines may wait
+// for the Promise to be resolved.
+//
+// Usage notes:
+//   - The zero value of Promise is ready to use.
+//   - A Promise should not be copied.
+type Promise[T any] struct {
+	mu struct {
+		sync.Mutex
+		done     chan struct{}
+		err      error
+		resolved bool
+		value    T
+	}
+}
+
+// Done returns a channel that will be closed once the Promise has been
+// resolved.
+func (p *Promise[T]) Done() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.doneLocked()
+}
+
+// Reject resolves the Promise with an error. This method returns false
+// if the Promise was already resolved.
+func (p *Promise[T]) Reject(err error) bool {
+	var zero T
+	return p.resolve(zero, err)
+}
+
+// Resolve resolves the Promise with a value. This method returns false
+// if the Promise was already resolved.
+func (p *Promise[T]) Resolve(value T) bool {
+	return p.resolve(value, nil)
+}
+
+// Wait blocks until the Promise has been resolved or the context has
+// been canceled.
+func (p *Promise[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-p.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.mu.value, p.mu.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (p *Promise[T]) doneLocked() chan struct{} {
+	if p.mu.done == nil {
+		p.mu.done = make(chan struct{})
+	}
+	return p.mu.done
+}
+
+func (p *Promise[T]) resolve(value T, err error) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.mu.resolved {
+		return false
+	}
+	p.mu.err = err
+	p.mu.resolved = true
+	p.mu.value = value
+	close(p.doneLocked())
+	return true
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromise(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var p Promise[int]
+	r.False(Changed(p.Done()))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := p.Wait(ctx)
+			r.NoError(err)
+			r.Equal(1, found)
+		}()
+	}
+
+	r.True(p.Resolve(1))
+	r.False(p.Resolve(2))
+	r.False(p.Reject(errors.New("ignored")))
+	wg.Wait()
+	r.True(Changed(p.Done()))
+}
+
+func TestPromiseReject(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var p Promise[int]
+	r.True(p.Reject(errors.New("expected")))
+	_, err := p.Wait(ctx)
+	r.ErrorContains(err, "expected")
+}
+
+func TestPromiseCancel(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var p Promise[int]
+	_, err := p.Wait(ctx)
+	r.ErrorIs(err, context.Canceled)
+}
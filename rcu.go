@@ -0,0 +1,98 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// RCU implements a read-copy-update pattern for large values which are
+// read far more often than they are written. Readers receive a pointer
+// to an immutable snapshot by way of a single atomic load. Writers
+// publish a modified copy of the snapshot and notify any listeners.
+//
+// Usage notes:
+//   - The zero value of RCU is ready to use and contains a nil
+//     snapshot.
+//   - An RCU should not be copied.
+//   - Snapshots must not be modified once they have been published.
+type RCU[T any] struct {
+	current atomic.Pointer[T]
+	reclaim func(old T)
+	updated Var[struct{}] // Notified after each publication.
+
+	writeMu sync.Mutex
+}
+
+// NewRCU constructs an RCU containing the initial snapshot. If the
+// reclaim function is non-nil, it will be called with a copy of each
+// replaced snapshot once the snapshot is no longer referenced by any
+// reader. That is, the grace period is determined by the garbage
+// collector. This allows resources held by the snapshot, such as pooled
+// buffers, to be recycled. Since [RCU.Update] makes a shallow copy, the
+// replaced snapshot may share slices, maps, or pointers with the
+// snapshot that replaced it. The reclaim function must only recycle a
+// resource if every update function replaces it with a deep copy;
+// otherwise, the resource will still be in use by a newer snapshot.
+func NewRCU[T any](initial *T, reclaim func(old T)) *RCU[T] {
+	r := &RCU[T]{reclaim: reclaim}
+	r.current.Store(initial)
+	return r
+}
+
+// Get returns the current snapshot and a channel that will be closed
+// when a new snapshot has been published.
+func (r *RCU[T]) Get() (*T, <-chan struct{}) {
+	// Acquire the channel first to avoid missing a publication.
+	_, changed := r.updated.Get()
+	return r.current.Load(), changed
+}
+
+// Load returns the current snapshot.
+func (r *RCU[T]) Load() *T {
+	return r.current.Load()
+}
+
+// Publish replaces the current snapshot and notifies any listeners.
+func (r *RCU[T]) Publish(next *T) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	r.publishLocked(next)
+}
+
+// Update calls the function with a shallow copy of the current
+// snapshot. If the function returns nil, the copy will be published.
+// The copy shares any slices, maps, or pointers with the current
+// snapshot, so the function must replace them with modified copies
+// rather than modifying them in place. The function may return
+// [ErrNoUpdate] to abort the update without returning an error. Calls
+// to Update are serialized, so no write will be lost.
+func (r *RCU[T]) Update(fn func(next *T) error) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	next := new(T)
+	if current := r.current.Load(); current != nil {
+		*next = *current
+	}
+	if err := fn(next); err != nil {
+		if errors.Is(err, ErrNoUpdate) {
+			return nil
+		}
+		return err
+	}
+	r.publishLocked(next)
+	return nil
+}
+
+func (r *RCU[T]) publishLocked(next *T) {
+	old := r.current.Swap(next)
+	if old != nil && old != next && r.reclaim != nil {
+		runtime.AddCleanup(old, r.reclaim, *old)
+	}
+	r.updated.Notify()
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type rcuConfig struct {
+	Name  string
+	Ports []int
+}
+
+func TestRCU(t *testing.T) {
+	r := require.New(t)
+
+	var rcu RCU[rcuConfig]
+	r.Nil(rcu.Load())
+
+	snap, changed := rcu.Get()
+	r.Nil(snap)
+	r.NoError(rcu.Update(func(next *rcuConfig) error {
+		next.Name = "first"
+		return nil
+	}))
+	r.True(Changed(changed))
+
+	first := rcu.Load()
+	r.Equal("first", first.Name)
+	r.NoError(rcu.Update(func(next *rcuConfig) error {
+		next.Ports = []int{80}
+		return nil
+	}))
+
+	// The earlier snapshot is unchanged.
+	r.Empty(first.Ports)
+	r.Equal(rcuConfig{"first", []int{80}}, *rcu.Load())
+
+	boom := errors.New("boom")
+	snap, changed = rcu.Get()
+	r.ErrorIs(rcu.Update(func(*rcuConfig) error { return boom }), boom)
+	r.NoError(rcu.Update(func(*rcuConfig) error { return ErrNoUpdate }))
+	r.Same(snap, rcu.Load())
+	r.False(Changed(changed))
+}
+
+func TestRCUAliasing(t *testing.T) {
+	r := require.New(t)
+
+	rcu := NewRCU(&rcuConfig{Name: "first", Ports: []int{80}}, nil)
+	first := rcu.Load()
+	r.NoError(rcu.Update(func(next *rcuConfig) error {
+		// The shallow copy shares the slice with the current snapshot.
+		r.Same(&first.Ports[0], &next.Ports[0])
+		next.Ports = slices.Clone(next.Ports)
+		next.Ports[0] = 443
+		return nil
+	}))
+
+	// Replacing the slice leaves the earlier snapshot intact.
+	r.Equal([]int{80}, first.Ports)
+	r.Equal([]int{443}, rcu.Load().Ports)
+}
+
+func TestRCUReclaim(t *testing.T) {
+	r := require.New(t)
+
+	reclaimed := make(chan string, 1)
+	rcu := NewRCU(&rcuConfig{Name: "first"}, func(old rcuConfig) {
+		reclaimed <- old.Name
+	})
+	rcu.Publish(&rcuConfig{Name: "second"})
+
+	r.Eventually(func() bool {
+		runtime.GC()
+		select {
+		case name := <-reclaimed:
+			r.Equal("first", name)
+			return true
+		default:
+			return false
+		}
+	}, time.Minute, time.Millisecond)
+}
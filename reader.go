@@ -0,0 +1,59 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import "context"
+
+// A Reader is a read-only view of a [Var]. It allows a subsystem to
+// observe a variable without being able to change its value. The zero
+// value of a Reader is not usable; Readers are obtained from
+// [Var.Reader].
+type Reader[T any] struct {
+	v *Var[T]
+}
+
+// Reader returns a read-only view of the Var.
+func (v *Var[T]) Reader() Reader[T] {
+	return Reader[T]{v}
+}
+
+// Closed is equivalent to [Var.Closed].
+func (r Reader[T]) Closed() bool {
+	return r.v.Closed()
+}
+
+// Get is equivalent to [Var.Get].
+func (r Reader[T]) Get() (T, <-chan struct{}) {
+	return r.v.Get()
+}
+
+// GetVersioned is equivalent to [Var.GetVersioned].
+func (r Reader[T]) GetVersioned() (T, uint64, <-chan struct{}) {
+	return r.v.GetVersioned()
+}
+
+// History is equivalent to [Var.History].
+func (r Reader[T]) History() []Entry[T] {
+	return r.v.History()
+}
+
+// Load is equivalent to [Var.Load].
+func (r Reader[T]) Load() T {
+	return r.v.Load()
+}
+
+// Peek is equivalent to [Var.Peek].
+func (r Reader[T]) Peek(fn func(value T) error) (<-chan struct{}, error) {
+	return r.v.Peek(fn)
+}
+
+// Wait is equivalent to [Var.Wait].
+func (r Reader[T]) Wait(ctx context.Context, pred func(T) bool) (T, error) {
+	return r.v.Wait(ctx, pred)
+}
+
+// Watch is equivalent to [Var.Watch].
+func (r Reader[T]) Watch(ctx context.Context) <-chan T {
+	return r.v.Watch(ctx)
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(1)
+	reader := v.Reader()
+
+	found, ch := reader.Get()
+	r.Equal(1, found)
+	r.Equal(1, reader.Load())
+
+	v.Set(2)
+	r.True(Changed(ch))
+
+	found, version, _ := reader.GetVersioned()
+	r.Equal(2, found)
+	r.Equal(uint64(1), version)
+
+	_, err := reader.Peek(func(value int) error {
+		r.Equal(2, value)
+		return nil
+	})
+	r.NoError(err)
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Leveler returns a [slog.Leveler] that reports the current value of
+// the Var. It may be used in [slog.HandlerOptions] to allow the logging
+// level to be changed at runtime.
+func Leveler(v *Var[slog.Level]) slog.Leveler {
+	return leveler(v.Load)
+}
+
+// NamedLeveler returns a [slog.Leveler] that reports the level for the
+// given name, as found in the Var's current map. If the map does not
+// contain the name, the fallback will be used. This allows the
+// verbosity of individual loggers to be adjusted independently.
+func NamedLeveler(v *Var[map[string]slog.Level], name string, fallback slog.Leveler) slog.Leveler {
+	return leveler(func() slog.Level {
+		var ret slog.Level
+		var ok bool
+		_, _ = v.Peek(func(levels map[string]slog.Level) error {
+			ret, ok = levels[name]
+			return nil
+		})
+		if !ok {
+			ret = fallback.Level()
+		}
+		return ret
+	})
+}
+
+// SyncLevelVar copies the current value of the Var into the
+// [slog.LevelVar] and starts a goroutine to copy any subsequent changes
+// until the context is canceled or the Var is closed. This is useful
+// for code which already accepts a *slog.LevelVar. Changes made
+// directly to the LevelVar will not be reflected in the Var, since a
+// LevelVar does not provide change notifications; they will be
+// overwritten by the next change to the Var.
+func SyncLevelVar(ctx context.Context, v *Var[slog.Level], lv *slog.LevelVar) {
+	level, changed := v.Get()
+	lv.Set(level)
+	go func() {
+		for {
+			select {
+			case <-changed:
+				var entry Entry[slog.Level]
+				var closed bool
+				entry, changed, closed = v.state()
+				lv.Set(entry.Value)
+				if closed {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+type leveler func() slog.Level
+
+func (l leveler) Level() slog.Level { return l() }
@@ -0,0 +1,72 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeveler(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(slog.LevelInfo)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: Leveler(v)}))
+
+	logger.Debug("hidden")
+	r.Empty(buf.String())
+
+	v.Set(slog.LevelDebug)
+	logger.Debug("shown")
+	r.Contains(buf.String(), "shown")
+}
+
+func TestNamedLeveler(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(map[string]slog.Level{"db": slog.LevelDebug})
+	db := NamedLeveler(v, "db", slog.LevelWarn)
+	http := NamedLeveler(v, "http", slog.LevelWarn)
+
+	r.Equal(slog.LevelDebug, db.Level())
+	r.Equal(slog.LevelWarn, http.Level())
+
+	v.Set(map[string]slog.Level{"http": slog.LevelError})
+	r.Equal(slog.LevelWarn, db.Level())
+	r.Equal(slog.LevelError, http.Level())
+}
+
+func TestSyncLevelVar(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(slog.LevelWarn)
+	var lv slog.LevelVar
+	SyncLevelVar(ctx, v, &lv)
+	r.Equal(slog.LevelWarn, lv.Level())
+
+	v.Set(slog.LevelDebug)
+	r.Eventually(func() bool {
+		return lv.Level() == slog.LevelDebug
+	}, time.Minute, time.Millisecond)
+
+	// The goroutine exits once the Var is closed, rather than spinning
+	// on its closed channel.
+	before := runtime.NumGoroutine()
+	v.Set(slog.LevelError)
+	v.Close()
+	for runtime.NumGoroutine() >= before {
+		r.NoError(ctx.Err())
+		time.Sleep(time.Millisecond)
+	}
+	r.Equal(slog.LevelError, lv.Level())
+}
@@ -0,0 +1,328 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrOverflow is reported by [Subscription.Err] when a subscription
+// using [OverflowFail] could not keep up with the Var.
+var ErrOverflow = errors.New("subscription overflowed")
+
+// An Overflow policy determines the behavior of a [Subscription] whose
+// buffer is full when a new value is stored.
+type Overflow int
+
+const (
+	// OverflowBlock causes writers to the Var to wait until the
+	// subscriber has made space in its buffer. Since the Var's lock is
+	// held while waiting, the subscriber must not access the Var from
+	// the goroutine that receives values.
+	OverflowBlock Overflow = iota
+	// OverflowDropOldest discards the oldest buffered value.
+	OverflowDropOldest
+	// OverflowFail terminates the subscription with [ErrOverflow].
+	OverflowFail
+	// OverflowConflate ignores the Buffer option and retains only the
+	// most recent value, discarding any value that has not yet been
+	// received. This is suitable for consumers, such as dashboards,
+	// that only need the latest state.
+	OverflowConflate
+)
+
+// A ListenerOption customizes a callback registered with
+// [Var.OnChange].
+type ListenerOption func(*listenerConfig)
+
+type listenerConfig struct {
+	sync bool
+}
+
+// Synchronous causes the callback to be invoked by the goroutine that
+// writes to the Var, before the write returns. The callback will
+// receive every change, but it is invoked while the Var's lock is held
+// and must not access the Var.
+func Synchronous() ListenerOption {
+	return func(cfg *listenerConfig) { cfg.sync = true }
+}
+
+// SubscribeOptions are passed to [Var.Subscribe].
+type SubscribeOptions struct {
+	// Buffer is the number of values, in addition to the replayed
+	// values, that may be buffered before the Overflow policy is
+	// applied.
+	Buffer int
+	// Overflow determines what happens when the buffer is full.
+	Overflow Overflow
+	// Replay is the number of values, ending with the current value,
+	// to deliver from the Var's history when the subscription is
+	// created. At least the current value is always delivered. See
+	// [Var.EnableHistory].
+	Replay int
+	// ReplayAfter, if non-nil, causes every retained value whose
+	// version is greater than *ReplayAfter to be delivered when the
+	// subscription is created, instead of applying Replay. This allows
+	// a consumer to resume from the last version that it processed. If
+	// the Var no longer retains every such value, [Subscription.Truncated]
+	// will report true.
+	ReplayAfter *uint64
+}
+
+// A Subscription receives every value that is stored in a Var, subject
+// to its [Overflow] policy. Unlike [Var.Get], which allows intermediate
+// values to be missed, a Subscription is suitable for consumers such as
+// audit logs. Subscriptions are constructed by [Var.Subscribe].
+type Subscription[T any] struct {
+	// C receives the current or replayed values of the Var when the
+	// subscription is created and every value that is subsequently
+	// stored. It will be closed when the subscription has ended.
+	C <-chan T
+
+	ch        chan T // Sent to or closed only while holding the Var's lock.
+	done      chan struct{}
+	doneOnce  sync.Once
+	err       error // Set before done is closed.
+	opts      SubscribeOptions
+	stop      func() bool // Stops the context.AfterFunc callback.
+	truncated bool        // See Truncated.
+	v         *Var[T]
+}
+
+// OnChange registers a callback that receives the old and new values of
+// the Var after each change. By default, the callback is invoked on a
+// dedicated goroutine and changes which occur while the callback is
+// running will be coalesced. See [Synchronous] to receive every change.
+// The returned function stops further callbacks. It must not be called
+// from within a synchronous callback.
+func (v *Var[T]) OnChange(fn func(old, new T), opts ...ListenerOption) (cancel func()) {
+	var cfg listenerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.sync {
+		key := &fn
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		if v.mu.listeners == nil {
+			v.mu.listeners = make(map[*func(old, new T)]struct{})
+		}
+		v.mu.listeners[key] = struct{}{}
+		return func() {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			delete(v.mu.listeners, key)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	old, version, changed := v.GetVersioned()
+	go func() {
+		for {
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+			var next Entry[T]
+			var closed bool
+			next, changed, closed = v.state()
+			if next.Version != version && ctx.Err() == nil {
+				fn(old, next.Value)
+			}
+			old, version = next.Value, next.Version
+			if closed {
+				return
+			}
+		}
+	}()
+	return cancel
+}
+
+// Subscribe returns a Subscription which receives every value stored in
+// the Var, preceded by the current value or by values replayed from the
+// Var's history. The subscription will end when the Var is closed, when
+// the context is canceled, or when [Subscription.Close] is called.
+// Values that do not change the Var, as determined by [WithEqual], are
+// not delivered.
+func (v *Var[T]) Subscribe(ctx context.Context, opts SubscribeOptions) *Subscription[T] {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	replay, truncated := v.replayLocked(opts)
+	size := max(opts.Buffer, 0) + max(len(replay), 1)
+	if opts.Overflow == OverflowConflate {
+		size = max(len(replay), 1)
+	}
+	ch := make(chan T, size)
+	s := &Subscription[T]{
+		C:         ch,
+		ch:        ch,
+		done:      make(chan struct{}),
+		opts:      opts,
+		truncated: truncated,
+		v:         v,
+	}
+	for _, value := range replay {
+		s.ch <- value
+	}
+	if v.mu.closed {
+		s.endLocked(ErrClosed)
+		return s
+	}
+	if v.mu.subscribers == nil {
+		v.mu.subscribers = make(map[*Subscription[T]]struct{})
+	}
+	v.mu.subscribers[s] = struct{}{}
+	s.stop = context.AfterFunc(ctx, func() { s.end(context.Cause(ctx)) })
+	return s
+}
+
+// Close ends the subscription. Any values that have already been
+// buffered may still be received from C.
+func (s *Subscription[T]) Close() {
+	s.end(ErrClosed)
+}
+
+// Done returns a channel that is closed when the subscription has
+// ended.
+func (s *Subscription[T]) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns nil while the subscription is active. Once the
+// subscription has ended, Err returns [ErrClosed] if the Var or the
+// Subscription was closed, [ErrOverflow], or the cause of the context's
+// cancellation.
+func (s *Subscription[T]) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Truncated returns true if [SubscribeOptions.ReplayAfter] was set and
+// some of the values stored after that version could not be replayed,
+// because they were no longer retained by the Var's history. A consumer
+// that observes a truncated replay should resynchronize its state from
+// the first value received, rather than treating it as the next value
+// in a contiguous sequence.
+func (s *Subscription[T]) Truncated() bool {
+	return s.truncated
+}
+
+// deliverLocked sends the value to the subscriber, applying the
+// overflow policy if necessary.
+func (s *Subscription[T]) deliverLocked(value T) {
+	select {
+	case s.ch <- value:
+		return
+	default:
+	}
+	switch s.opts.Overflow {
+	case OverflowBlock:
+		select {
+		case s.ch <- value:
+		case <-s.done:
+			// The subscription was ended by another goroutine, which
+			// is waiting for the lock to remove it.
+		}
+	case OverflowConflate, OverflowDropOldest:
+		// Only a writer, which holds the lock, can fill the channel, so
+		// there will be space after discarding one value.
+		select {
+		case <-s.ch:
+		default:
+		}
+		s.ch <- value
+	case OverflowFail:
+		s.endLocked(ErrOverflow)
+	}
+}
+
+// end ends the subscription from a goroutine that does not hold the
+// Var's lock.
+func (s *Subscription[T]) end(err error) {
+	// Signal a blocked writer before acquiring the lock.
+	if !s.setErr(err) {
+		return
+	}
+	s.v.mu.Lock()
+	defer s.v.mu.Unlock()
+	s.removeLocked()
+}
+
+// endLocked ends the subscription while holding the Var's lock.
+func (s *Subscription[T]) endLocked(err error) {
+	if s.setErr(err) {
+		s.removeLocked()
+	}
+}
+
+func (s *Subscription[T]) removeLocked() {
+	if s.stop != nil {
+		s.stop()
+	}
+	delete(s.v.mu.subscribers, s)
+	close(s.ch)
+}
+
+// setErr records the error and closes the done channel, returning true
+// if this is the first call.
+func (s *Subscription[T]) setErr(err error) (first bool) {
+	s.doneOnce.Do(func() {
+		s.err = err
+		close(s.done)
+		first = true
+	})
+	return first
+}
+
+// replayLocked returns the values that a new subscription should
+// receive and whether any values requested by
+// [SubscribeOptions.ReplayAfter] were unavailable.
+func (v *Var[T]) replayLocked(opts SubscribeOptions) (_ []T, truncated bool) {
+	entries := v.mu.history.entries()
+	if len(entries) == 0 {
+		entries = []Entry[T]{{Value: v.mu.data, Version: v.mu.version}}
+	}
+	if after := opts.ReplayAfter; after != nil {
+		idx := 0
+		for idx < len(entries) && entries[idx].Version <= *after {
+			idx++
+		}
+		entries = entries[idx:]
+		truncated = len(entries) > 0 && entries[0].Version > *after+1
+	} else if n := max(opts.Replay, 1); n < len(entries) {
+		entries = entries[len(entries)-n:]
+	}
+	ret := make([]T, len(entries))
+	for i, entry := range entries {
+		ret[i] = entry.Value
+	}
+	return ret, truncated
+}
+
+// publishLocked invokes synchronous listeners and delivers the current
+// value to all subscribers.
+func (v *Var[T]) publishLocked(old T) {
+	for fn := range v.mu.listeners {
+		(*fn)(old, v.mu.data)
+	}
+	for s := range v.mu.subscribers {
+		s.deliverLocked(v.mu.data)
+	}
+}
+
+// unsubscribeAllLocked ends all subscriptions when the Var is closed.
+func (v *Var[T]) unsubscribeAllLocked() {
+	for s := range v.mu.subscribers {
+		s.endLocked(ErrClosed)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeBlock(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	sub := v.Subscribe(ctx, SubscribeOptions{Overflow: OverflowBlock})
+	go func() {
+		for i := 1; i <= 100; i++ {
+			v.Set(i)
+		}
+		v.Close()
+	}()
+
+	// Every value is received.
+	expected := 0
+	for found := range sub.C {
+		r.Equal(expected, found)
+		expected++
+	}
+	r.Equal(101, expected)
+	r.ErrorIs(sub.Err(), ErrClosed)
+}
+
+func TestSubscribeDropOldest(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	sub := v.Subscribe(ctx, SubscribeOptions{Buffer: 2, Overflow: OverflowDropOldest})
+	for i := 1; i <= 10; i++ {
+		v.Set(i)
+	}
+	r.Equal(8, <-sub.C)
+	r.Equal(9, <-sub.C)
+	r.Equal(10, <-sub.C)
+	r.NoError(sub.Err())
+
+	// Buffered values remain after the subscription ends.
+	v.Set(11)
+	sub.Close()
+	r.ErrorIs(sub.Err(), ErrClosed)
+	r.Equal(11, <-sub.C)
+	_, open := <-sub.C
+	r.False(open)
+}
+
+func TestSubscribeFail(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	sub := v.Subscribe(ctx, SubscribeOptions{Buffer: 1, Overflow: OverflowFail})
+	v.Set(1)
+	r.NoError(sub.Err())
+	v.Set(2)
+	r.ErrorIs(sub.Err(), ErrOverflow)
+	r.Equal(0, <-sub.C)
+	r.Equal(1, <-sub.C)
+	_, open := <-sub.C
+	r.False(open)
+}
+
+func TestSubscribeCanceled(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	v := VarOf(0)
+	sub := v.Subscribe(ctx, SubscribeOptions{Overflow: OverflowBlock})
+
+	// A blocked writer is released when the context is canceled.
+	r.Equal(0, <-sub.C)
+	v.Set(1)
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		v.Set(2)
+	}()
+	cancel()
+	<-written
+	<-sub.Done()
+	r.ErrorIs(sub.Err(), context.Canceled)
+
+	// A closed Var produces a completed subscription.
+	v.Close()
+	sub = v.Subscribe(context.Background(), SubscribeOptions{})
+	r.Equal(2, <-sub.C)
+	r.ErrorIs(sub.Err(), ErrClosed)
+}
+
+func TestOnChange(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(0)
+	type change struct{ old, new int }
+	async := make(chan change, 1)
+	var sync []change
+
+	cancelAsync := v.OnChange(func(old, new int) { async <- change{old, new} })
+	cancelSync := v.OnChange(func(old, new int) {
+		sync = append(sync, change{old, new})
+	}, Synchronous())
+
+	v.Set(1)
+	r.Equal(change{0, 1}, <-async)
+	v.Set(2)
+	r.Equal(change{1, 2}, <-async)
+	r.Equal([]change{{0, 1}, {1, 2}}, sync)
+
+	cancelSync()
+	cancelAsync()
+	v.Set(3)
+	r.Len(sync, 2)
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// receive drains and closes the subscription.
+	receive := func(sub *Subscription[int]) []int {
+		defer sub.Close()
+		var ret []int
+		for {
+			select {
+			case value := <-sub.C:
+				ret = append(ret, value)
+			default:
+				return ret
+			}
+		}
+	}
+
+	after8, after10 := uint64(8), uint64(10)
+	v := VarOf(0)
+
+	// Without history, only the current value is available.
+	r.Equal([]int{0}, receive(v.Subscribe(ctx, SubscribeOptions{Replay: 5})))
+
+	v.EnableHistory(5)
+	for i := 1; i <= 10; i++ {
+		v.Set(i)
+	}
+	r.Equal([]int{10}, receive(v.Subscribe(ctx, SubscribeOptions{})))
+	r.Equal([]int{8, 9, 10}, receive(v.Subscribe(ctx, SubscribeOptions{Replay: 3})))
+	r.Equal([]int{6, 7, 8, 9, 10}, receive(v.Subscribe(ctx, SubscribeOptions{Replay: 100})))
+	r.Equal([]int{9, 10}, receive(v.Subscribe(ctx, SubscribeOptions{ReplayAfter: &after8})))
+	r.Empty(receive(v.Subscribe(ctx, SubscribeOptions{ReplayAfter: &after10})))
+
+	// Live values follow the replayed values.
+	sub := v.Subscribe(ctx, SubscribeOptions{Buffer: 1, Replay: 2})
+	v.Set(11)
+	r.Equal([]int{9, 10, 11}, receive(sub))
+}
+
+func TestSubscribeReplayAfter(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	after := func(version uint64) SubscribeOptions {
+		return SubscribeOptions{ReplayAfter: &version}
+	}
+	receive := func(sub *Subscription[int]) ([]int, bool) {
+		defer sub.Close()
+		var ret []int
+		for {
+			select {
+			case value := <-sub.C:
+				ret = append(ret, value)
+			default:
+				return ret, sub.Truncated()
+			}
+		}
+	}
+
+	// The initial value has version zero.
+	v := VarOf(0)
+	v.EnableHistory(3)
+	v.Set(1)
+	values, truncated := receive(v.Subscribe(ctx, after(0)))
+	r.Equal([]int{1}, values)
+	r.False(truncated)
+
+	// Versions that are no longer retained are reported.
+	v.Set(2)
+	v.Set(3)
+	v.Set(4)
+	values, truncated = receive(v.Subscribe(ctx, after(0)))
+	r.Equal([]int{2, 3, 4}, values)
+	r.True(truncated)
+	values, truncated = receive(v.Subscribe(ctx, after(1)))
+	r.Equal([]int{2, 3, 4}, values)
+	r.False(truncated)
+
+	// Without history, only the current value can be replayed.
+	v.EnableHistory(0)
+	values, truncated = receive(v.Subscribe(ctx, after(3)))
+	r.Equal([]int{4}, values)
+	r.False(truncated)
+	values, truncated = receive(v.Subscribe(ctx, after(1)))
+	r.Equal([]int{4}, values)
+	r.True(truncated)
+	values, truncated = receive(v.Subscribe(ctx, after(4)))
+	r.Empty(values)
+	r.False(truncated)
+}
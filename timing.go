@@ -0,0 +1,117 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// Debounce returns a Var that is updated with the value of the source
+// once the source has not changed for the given duration. This is
+// useful for coalescing a burst of changes into a single update. The
+// returned Var will be closed, after receiving any pending value, once
+// the source has been closed or the context has been canceled.
+func Debounce[T any](ctx context.Context, source *Var[T], d time.Duration) *Derived[T] {
+	data, version, changed := source.GetVersioned()
+	return derive(ctx, data, func(ctx context.Context, out *Var[T]) {
+		timer := time.NewTimer(d)
+		timer.Stop()
+		emitted := version
+		for {
+			select {
+			case <-changed:
+				var entry Entry[T]
+				var closed bool
+				entry, changed, closed = source.state()
+				data, version = entry.Value, entry.Version
+				if closed {
+					if version != emitted {
+						out.Set(data)
+					}
+					return
+				}
+				timer.Reset(d)
+			case <-timer.C:
+				emitted = version
+				out.Set(data)
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// Sample returns a Var that is updated with the current value of the
+// source at a fixed interval, regardless of whether the source has
+// changed. This is useful for taking periodic snapshots of a variable
+// that changes frequently. The returned Var will be closed, after
+// receiving the final value, once the source has been closed or the
+// context has been canceled.
+func Sample[T any](ctx context.Context, source *Var[T], every time.Duration) *Derived[T] {
+	return derive(ctx, source.Load(), func(ctx context.Context, out *Var[T]) {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				entry, _, closed := source.state()
+				out.Set(entry.Value)
+				if closed {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// Throttle returns a Var that is updated with the value of the source
+// at most once per interval. A change to the source is propagated
+// immediately if the interval has elapsed since the previous update;
+// otherwise, it is delayed until the interval has elapsed. The returned
+// Var will always receive the latest value of the source. It will be
+// closed, after receiving any pending value, once the source has been
+// closed or the context has been canceled.
+func Throttle[T any](ctx context.Context, source *Var[T], interval time.Duration) *Derived[T] {
+	data, version, changed := source.GetVersioned()
+	return derive(ctx, data, func(ctx context.Context, out *Var[T]) {
+		timer := time.NewTimer(interval)
+		timer.Stop()
+		emitted := version
+		var last time.Time
+		waiting := false
+		for {
+			select {
+			case <-changed:
+				var entry Entry[T]
+				var closed bool
+				entry, changed, closed = source.state()
+				data, version = entry.Value, entry.Version
+				if closed {
+					if version != emitted {
+						out.Set(data)
+					}
+					return
+				}
+				if waiting {
+					continue
+				}
+				if wait := interval - time.Since(last); wait > 0 {
+					waiting = true
+					timer.Reset(wait)
+					continue
+				}
+			case <-timer.C:
+				waiting = false
+			case <-ctx.Done():
+				return
+			}
+			emitted = version
+			last = time.Now()
+			out.Set(data)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebounce(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	source := VarOf(0)
+	out := Debounce(ctx, source, 50*time.Millisecond)
+	r.Equal(0, out.Load())
+
+	_, _, changed := out.GetVersioned()
+	for i := 1; i <= 10; i++ {
+		source.Set(i)
+	}
+	<-changed
+	data, version, _ := out.GetVersioned()
+	r.Equal(10, data)
+	r.Equal(uint64(1), version)
+
+	// A pending value is delivered when the source is closed.
+	source.Set(11)
+	source.Close()
+	_, err := out.Wait(ctx, func(int) bool { return out.Closed() })
+	r.NoError(err)
+	r.Equal(11, out.Load())
+}
+
+func TestDebounceCanceled(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	out := Debounce(ctx, VarOf(0), time.Hour)
+	cancel()
+	<-out.changed()
+	r.True(out.Closed())
+}
+
+func TestThrottle(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	source := VarOf(0)
+	out := Throttle(ctx, source, 50*time.Millisecond)
+	r.Equal(0, out.Load())
+
+	// The first change is propagated immediately and subsequent changes
+	// are delayed until the interval has elapsed.
+	start := time.Now()
+	source.Set(1)
+	_, err := out.Wait(ctx, func(i int) bool { return i == 1 })
+	r.NoError(err)
+	for i := 2; i <= 10; i++ {
+		source.Set(i)
+	}
+	_, err = out.Wait(ctx, func(i int) bool { return i == 10 })
+	r.NoError(err)
+	r.GreaterOrEqual(time.Since(start), 50*time.Millisecond)
+	_, version, _ := out.GetVersioned()
+	r.LessOrEqual(version, uint64(3))
+
+	// A pending value is delivered when the source is closed.
+	source.Set(11)
+	source.Close()
+	_, err = out.Wait(ctx, func(int) bool { return out.Closed() })
+	r.NoError(err)
+	r.Equal(11, out.Load())
+}
+
+func TestSample(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	source := VarOf(0)
+	out := Sample(ctx, source, time.Millisecond)
+	r.Equal(0, out.Load())
+
+	// Updates occur without any change to the source.
+	_, err := out.Wait(ctx, func(int) bool {
+		_, version, _ := out.GetVersioned()
+		return version >= 3
+	})
+	r.NoError(err)
+	r.Equal(0, out.Load())
+
+	source.Set(1)
+	_, err = out.Wait(ctx, func(i int) bool { return i == 1 })
+	r.NoError(err)
+
+	source.Set(2)
+	source.Close()
+	_, err = out.Wait(ctx, func(int) bool { return out.Closed() })
+	r.NoError(err)
+	r.Equal(2, out.Load())
+}
@@ -0,0 +1,26 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import "context"
+
+// Value is the behavior of a [Var], expressed as an interface. Libraries
+// may accept a Value to allow callers to substitute an alternate
+// implementation, such as a fake that injects failures in tests.
+type Value[T any] interface {
+	// Get is equivalent to [Var.Get].
+	Get() (T, <-chan struct{})
+	// Load is equivalent to [Var.Load].
+	Load() T
+	// Peek is equivalent to [Var.Peek].
+	Peek(fn func(value T) error) (<-chan struct{}, error)
+	// Set is equivalent to [Var.Set].
+	Set(next T) <-chan struct{}
+	// Update is equivalent to [Var.Update].
+	Update(fn func(old T) (new T, _ error)) (T, <-chan struct{}, error)
+	// Watch is equivalent to [Var.Watch].
+	Watch(ctx context.Context) <-chan T
+}
+
+var _ Value[any] = (*Var[any])(nil)
@@ -0,0 +1,47 @@
+// Copyright 2025 Bob Vawter (bob@vawter.org)
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingValue demonstrates substituting a Value implementation.
+type failingValue[T any] struct {
+	Value[T]
+	sets int
+}
+
+func (f *failingValue[T]) Update(func(T) (T, error)) (T, <-chan struct{}, error) {
+	ret, ch := f.Get()
+	return ret, ch, errors.New("injected")
+}
+
+func (f *failingValue[T]) Set(next T) <-chan struct{} {
+	f.sets++
+	return f.Value.Set(next)
+}
+
+func TestValue(t *testing.T) {
+	r := require.New(t)
+
+	// A library function that accepts a Value.
+	increment := func(v Value[int]) error {
+		_, _, err := v.Update(func(old int) (int, error) { return old + 1, nil })
+		return err
+	}
+
+	v := VarOf(1)
+	r.NoError(increment(v))
+	r.Equal(2, v.Load())
+
+	fake := &failingValue[int]{Value: v}
+	r.ErrorContains(increment(fake), "injected")
+	fake.Set(3)
+	r.Equal(1, fake.sets)
+	r.Equal(3, v.Load())
+}
@@ -6,10 +6,25 @@
 package notify
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"time"
+
+	"vawter.tech/notify/internal/varstate"
 )
 
+func init() {
+	varstate.Get = func(v any) (any, uint64, <-chan struct{}, bool) {
+		return v.(interface {
+			stateAny() (any, uint64, <-chan struct{}, bool)
+		}).stateAny()
+	}
+}
+
+// ErrClosed is returned by [Var.Update] if the Var has been closed.
+var ErrClosed = errors.New("variable closed")
+
 // ErrNoUpdate is a sentinel value that can be returned by the callback
 // passed to [Var.Update].
 var ErrNoUpdate = errors.New("no update required")
@@ -24,24 +39,165 @@ var ErrNoUpdate = errors.New("no update required")
 //     goroutines. If the value contained by the Var is mutable, the
 //     [Var.Peek] and [Var.Update] methods should be used to
 //     ensure race-free behavior.
+//   - By default, every write will notify listeners. Use [WithEqual]
+//     to suppress notifications for writes that do not change the
+//     value.
+//   - A Var may be closed to indicate that its value will never change
+//     again. See [Var.Close].
 type Var[T any] struct {
+	eq           func(a, b T) bool // See WithEqual.
+	interceptors []Interceptor[T]  // See WithInterceptor.
+	validate     func(T) error     // See WithValidator.
+
 	mu struct {
 		sync.RWMutex
-		data    T
-		updated chan struct{}
+		closed      bool
+		data        T
+		history     historyRing[T]                 // See EnableHistory.
+		listeners   map[*func(old, new T)]struct{} // See OnChange.
+		refreshing  chan struct{}                  // Non-nil while GetFresh is refreshing.
+		stored      time.Time                      // The time at which data was stored.
+		subscribers map[*Subscription[T]]struct{}
+		updated     chan struct{}
+		version     uint64                 // Incremented by each notification.
+		wakers      map[*aggEntry]struct{} // See Aggregation.
 	}
 }
 
 // VarOf constructs a Var set to the initial value.
-func VarOf[T any](initial T) *Var[T] {
+func VarOf[T any](initial T, opts ...Option[T]) *Var[T] {
 	ret := &Var[T]{}
+	for _, opt := range opts {
+		opt(ret)
+	}
 	ret.mu.data = initial
+	ret.mu.stored = time.Now()
 	ret.mu.updated = make(chan struct{})
 	return ret
 }
 
+// CompareAndSwap atomically stores the next value in the variable if
+// its current value is equal to old. Listeners are notified only if the
+// swap occurs.
+//
+// This should be a method whenever Go supports generic methods.
+func CompareAndSwap[T comparable](v *Var[T], old, next T) (swapped bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.mu.data != old {
+		return false
+	}
+	_, err := v.storeLocked(next)
+	return err == nil
+}
+
+// CompareAndSwapVersion atomically stores the next value in the
+// variable if its current version, as reported by [Var.GetVersioned],
+// is equal to the given version. Unlike [CompareAndSwap], this detects
+// a variable that has changed and then returned to an earlier value.
+// Listeners are notified only if the swap occurs.
+func (v *Var[T]) CompareAndSwapVersion(version uint64, next T) (swapped bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.mu.version != version {
+		return false
+	}
+	_, err := v.storeLocked(next)
+	return err == nil
+}
+
+// SetIfChanged stores the next value in the variable only if it differs
+// from the current value. This method returns true if listeners were
+// notified of a change.
+//
+// This should be a method whenever Go supports generic methods.
+func SetIfChanged[T comparable](v *Var[T], next T) (changed bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.mu.data == next {
+		return false
+	}
+	changed, _ = v.storeLocked(next)
+	return changed
+}
+
+// Close places the Var into a terminal state, indicating to listeners
+// that its value will never change again. The current notification
+// channel will be closed and will not be replaced, so all subsequent
+// calls to Get will return a closed channel. Callers that observe a
+// closed channel should use [Var.Closed] to distinguish a change from
+// closure. Writes to a closed Var are discarded and Update will return
+// [ErrClosed]. Calling Close more than once has no effect.
+func (v *Var[T]) Close() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.mu.closed {
+		return
+	}
+	v.mu.closed = true
+	if v.mu.updated == nil {
+		v.mu.updated = make(chan struct{})
+	}
+	close(v.mu.updated)
+	v.wakeLocked()
+	v.unsubscribeAllLocked()
+}
+
+// Closed returns true if [Var.Close] has been called.
+func (v *Var[T]) Closed() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.mu.closed
+}
+
+// state returns the current value and its metadata, the notification
+// channel, and whether the Var has been closed. Since these are read
+// under a single lock, a caller that observes the Var to be closed has
+// also observed its final value and should not wait on the channel,
+// which will never be replaced.
+func (v *Var[T]) state() (_ Entry[T], changed <-chan struct{}, closed bool) {
+	v.mu.RLock()
+	entry := v.entryLocked()
+	changed, closed = v.mu.updated, v.mu.closed
+	v.mu.RUnlock()
+	if changed != nil {
+		return entry, changed, closed
+	}
+
+	// Called on zero value, may need to initialize.
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.mu.updated == nil {
+		v.mu.updated = make(chan struct{})
+	}
+	return v.entryLocked(), v.mu.updated, v.mu.closed
+}
+
+// stateAny is used to implement [varstate.Get].
+func (v *Var[T]) stateAny() (any, uint64, <-chan struct{}, bool) {
+	entry, changed, closed := v.state()
+	return entry.Value, entry.Version, changed, closed
+}
+
+// entryLocked returns the current value and its metadata.
+func (v *Var[T]) entryLocked() Entry[T] {
+	return Entry[T]{
+		Stored:  v.mu.stored,
+		Value:   v.mu.data,
+		Version: v.mu.version,
+	}
+}
+
 // Get returns the current (possibly zero) value for T and a channel
-// that will be closed the next time that Set or Update is called.
+// that will be closed the next time that listeners are notified. That
+// is, when a new value is stored, when [Var.Notify] is called, or when
+// the Var is closed. A write that is rejected by an interceptor or a
+// validator, or that a comparator configured with [WithEqual] considers
+// to be equal to the current value, does not close the channel.
 func (v *Var[T]) Get() (T, <-chan struct{}) {
 	v.mu.RLock()
 	data, ch := v.mu.data, v.mu.updated
@@ -59,6 +215,111 @@ func (v *Var[T]) Get() (T, <-chan struct{}) {
 	return v.mu.data, v.mu.updated
 }
 
+// GetFresh returns the current value if it was stored within maxAge.
+// Otherwise, the refresh callback will be invoked to compute a new
+// value, which will then be stored in the variable. If multiple callers
+// observe a stale value, only one will invoke the callback while the
+// others wait for it to complete and receive the refreshed value. If
+// the callback returns an error, the stale value will be returned
+// alongside the error. A zero-valued Var is always considered to be
+// stale.
+func (v *Var[T]) GetFresh(
+	ctx context.Context, maxAge time.Duration, refresh func(ctx context.Context) (T, error),
+) (T, error) {
+	for {
+		v.mu.RLock()
+		data, stored := v.mu.data, v.mu.stored
+		v.mu.RUnlock()
+		if !stored.IsZero() && time.Since(stored) <= maxAge {
+			return data, nil
+		}
+
+		v.mu.Lock()
+		if v.mu.stored != stored {
+			// Raced with another writer, so re-check the age.
+			v.mu.Unlock()
+			continue
+		}
+		if wait := v.mu.refreshing; wait != nil {
+			v.mu.Unlock()
+			select {
+			case <-wait:
+				// Accept any value produced by the other caller.
+				v.mu.RLock()
+				data, refreshed := v.mu.data, v.mu.stored != stored
+				v.mu.RUnlock()
+				if refreshed {
+					return data, nil
+				}
+				continue
+			case <-ctx.Done():
+				return data, ctx.Err()
+			}
+		}
+		done := make(chan struct{})
+		v.mu.refreshing = done
+		v.mu.Unlock()
+		return v.runRefresh(ctx, done, refresh)
+	}
+}
+
+// runRefresh invokes the callback on behalf of [Var.GetFresh] and
+// stores its value. The done channel is cleared and closed even if the
+// callback panics, so that waiting callers are not blocked forever.
+func (v *Var[T]) runRefresh(
+	ctx context.Context, done chan struct{}, refresh func(ctx context.Context) (T, error),
+) (T, error) {
+	defer func() {
+		v.mu.Lock()
+		v.mu.refreshing = nil
+		close(done)
+		v.mu.Unlock()
+	}()
+
+	next, err := refresh(ctx)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err == nil {
+		_, err = v.storeLocked(next)
+	}
+	return v.mu.data, err
+}
+
+// GetVersioned returns the current (possibly zero) value for T, its
+// version, and a channel that will be closed as described by
+// [Var.Get]. The version is a monotonic counter that increases by one
+// each time listeners are notified. It may be used to detect how many
+// updates a consumer has missed or to make downstream processing
+// idempotent.
+func (v *Var[T]) GetVersioned() (T, uint64, <-chan struct{}) {
+	v.mu.RLock()
+	data, version, ch := v.mu.data, v.mu.version, v.mu.updated
+	v.mu.RUnlock()
+	if ch != nil {
+		return data, version, ch
+	}
+
+	// Called on zero value, may need to initialize.
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.mu.updated == nil {
+		v.mu.updated = make(chan struct{})
+	}
+	return v.mu.data, v.mu.version, v.mu.updated
+}
+
+// Load returns the current (possibly zero) value for T. This is
+// suitable for hot paths that do not need a notification channel. Load
+// holds only a read lock and does not allocate, whereas calling Get on
+// a zero-valued Var may need to acquire a write lock to allocate the
+// notification channel.
+func (v *Var[T]) Load() T {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.mu.data
+}
+
 // Notify behaves as though Set was called with the current value.
 // That is, it replaces the notification channel.
 func (v *Var[T]) Notify() {
@@ -80,50 +341,295 @@ func (v *Var[T]) Peek(fn func(value T) error) (<-chan struct{}, error) {
 // Set updates the value and notifies any listeners. The notification
 // channel is returned to avoid a race condition if a caller wants to
 // set the value and receive a notification if another caller has
-// subsequently updated it.
+// subsequently updated it. A value rejected by a validator (see
+// [WithValidator]) is discarded; use [Var.TrySet] to observe the error.
 func (v *Var[T]) Set(next T) <-chan struct{} {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	v.mu.data = next
-	v.notifyLocked()
+	_, _ = v.storeLocked(next)
 	return v.mu.updated
 }
 
-// Swap returns the current value and a channel that will be closed
-// when the next value has been replaced.
+// SetVersioned behaves like [Var.Set], additionally returning the
+// version of the newly-stored value. See [Var.GetVersioned].
+func (v *Var[T]) SetVersioned(next T) (uint64, <-chan struct{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, _ = v.storeLocked(next)
+	return v.mu.version, v.mu.updated
+}
+
+// Swap atomically stores the next value and returns the value that it
+// replaced. The returned channel will be closed when the next value has
+// been replaced. Unlike calling Get followed by Set, there is no window
+// in which another caller may update the variable.
 func (v *Var[T]) Swap(next T) (T, <-chan struct{}) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
 	ret := v.mu.data
-	v.mu.data = next
-	v.notifyLocked()
+	_, _ = v.storeLocked(next)
 	return ret, v.mu.updated
 }
 
+// TrySet behaves like [Var.Set], but returns an error if the Var has
+// been closed or if the next value is rejected by a validator. The Var
+// is unchanged if an error is returned.
+func (v *Var[T]) TrySet(next T) (<-chan struct{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, err := v.storeLocked(next)
+	return v.mu.updated, err
+}
+
 // Update atomically updates the stored value using the current value as
 // an input. The callback may return [ErrNoUpdate] to take no action;
 // this error will not be returned to the caller. If the callback
-// returns any other error, no action is taken and the unchanged value
-// is returned.
+// returns any other error, or if the new value is rejected by a
+// validator, no action is taken and the unchanged value is returned.
 func (v *Var[T]) Update(fn func(old T) (new T, _ error)) (T, <-chan struct{}, error) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
+	err := v.updateLocked(fn)
+	return v.mu.data, v.mu.updated, err
+}
+
+// UpdateContext behaves like [Var.Update], but allows the callback to
+// respect context cancellation. If the context is done before the
+// callback is invoked or by the time that it returns, the update will
+// be aborted and the context's error will be returned. The callback is
+// invoked while holding the Var's write lock.
+func (v *Var[T]) UpdateContext(
+	ctx context.Context, fn func(ctx context.Context, old T) (new T, _ error),
+) (T, <-chan struct{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	err := v.updateLocked(func(old T) (T, error) {
+		if err := ctx.Err(); err != nil {
+			return old, err
+		}
+		next, err := fn(ctx, old)
+		if err == nil {
+			err = ctx.Err()
+		}
+		return next, err
+	})
+	return v.mu.data, v.mu.updated, err
+}
+
+// UpdateIf atomically updates the stored value using the current value
+// as an input. The callback may decline to change the value by
+// returning false. This method returns true if the new value was
+// written. A value may also fail to be written if the Var has been
+// closed or if the value is rejected by a validator.
+func (v *Var[T]) UpdateIf(fn func(old T) (new T, apply bool)) (T, <-chan struct{}, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	next, apply := fn(v.mu.data)
+	if apply {
+		_, err := v.storeLocked(next)
+		apply = err == nil
+	}
+	return v.mu.data, v.mu.updated, apply
+}
+
+// UpdateVersioned behaves like [Var.Update], additionally returning the
+// version of the resulting value. See [Var.GetVersioned].
+func (v *Var[T]) UpdateVersioned(
+	fn func(old T) (new T, _ error),
+) (T, uint64, <-chan struct{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	err := v.updateLocked(fn)
+	return v.mu.data, v.mu.version, v.mu.updated, err
+}
+
+// Wait blocks until the value of the Var satisfies the predicate or the
+// context is done, returning the most recently observed value. If the
+// Var is closed before the predicate is satisfied, [ErrClosed] will be
+// returned.
+func (v *Var[T]) Wait(ctx context.Context, pred func(T) bool) (T, error) {
+	for {
+		entry, changed, closed := v.state()
+		if pred(entry.Value) {
+			return entry.Value, nil
+		}
+		if closed {
+			return entry.Value, ErrClosed
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return entry.Value, ctx.Err()
+		}
+	}
+}
+
+// Watch returns a channel that receives the current value of the Var
+// and then the latest value after each change. Intermediate values will
+// be skipped if the receiver falls behind. The channel will be closed
+// when the context is canceled or after the final value of a closed Var
+// has been received.
+func (v *Var[T]) Watch(ctx context.Context) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			entry, changed, closed := v.state()
+			replaced := changed
+			if closed {
+				replaced = nil
+			}
+			select {
+			case out <- entry.Value:
+				if closed {
+					return
+				}
+				select {
+				case <-changed:
+				case <-ctx.Done():
+					return
+				}
+			case <-replaced:
+				// Send the latest value instead.
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// arm implements [UntypedVar]. The entry will be fired once, when the
+// Var next changes or is closed. This method returns false if the Var
+// has already been closed.
+func (v *Var[T]) arm(entry *aggEntry) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.mu.closed {
+		return false
+	}
+	if v.mu.wakers == nil {
+		v.mu.wakers = make(map[*aggEntry]struct{})
+	}
+	v.mu.wakers[entry] = struct{}{}
+	return true
+}
+
+// changed implements [UntypedVar].
+func (v *Var[T]) changed() <-chan struct{} {
+	_, ch := v.Get()
+	return ch
+}
+
+// disarm implements [UntypedVar].
+func (v *Var[T]) disarm(entry *aggEntry) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.mu.wakers, entry)
+}
+
+// readLock implements [UntypedVar].
+func (v *Var[T]) readLock() (any, func(), bool) {
+	v.mu.RLock()
+	return v.mu.data, v.mu.RUnlock, true
+}
+
+// storeLocked replaces the current value and notifies any listeners.
+// An error will be returned if the Var has been closed or if the value
+// is rejected by an interceptor or the validator. If the Var has a
+// comparator that considers the next value to be equal to the current
+// value, this method will return false.
+func (v *Var[T]) storeLocked(next T) (changed bool, _ error) {
+	if v.mu.closed {
+		return false, ErrClosed
+	}
+	next, ok, err := v.resolveLocked(next)
+	if err != nil || !ok {
+		return false, err
+	}
+	return v.commitLocked(next), nil
+}
+
+// resolveLocked runs the interceptors and the validator without
+// modifying the Var and returns the value that should be committed. If
+// an interceptor does not proceed with the write, ok will be false.
+func (v *Var[T]) resolveLocked(next T) (resolved T, ok bool, _ error) {
+	// Build the chain from the innermost interceptor outwards.
+	proceed := func(next T) error {
+		if v.validate != nil {
+			if err := v.validate(next); err != nil {
+				return err
+			}
+		}
+		resolved, ok = next, true
+		return nil
+	}
+	for i := len(v.interceptors) - 1; i >= 0; i-- {
+		intercept, inner := v.interceptors[i], proceed
+		proceed = func(next T) error {
+			return intercept(v.mu.data, next, inner)
+		}
+	}
+	if err := proceed(next); err != nil {
+		return resolved, false, err
+	}
+	return resolved, ok, nil
+}
+
+// commitLocked stores a value which has been returned from
+// resolveLocked and notifies any listeners.
+func (v *Var[T]) commitLocked(next T) (changed bool) {
+	v.mu.stored = time.Now()
+	if v.eq != nil && v.eq(v.mu.data, next) {
+		return false
+	}
+	old := v.mu.data
+	v.mu.data = next
+	v.notifyLocked()
+	v.recordLocked()
+	v.publishLocked(old)
+	return true
+}
+
+// updateLocked implements [Var.Update].
+func (v *Var[T]) updateLocked(fn func(old T) (new T, _ error)) error {
+	if v.mu.closed {
+		return ErrClosed
+	}
 	next, err := fn(v.mu.data)
 	if err == nil {
-		v.mu.data = next
-		v.notifyLocked()
+		_, err = v.storeLocked(next)
 	} else if errors.Is(err, ErrNoUpdate) {
 		err = nil
 	}
-	return v.mu.data, v.mu.updated, err
+	return err
 }
 
 func (v *Var[T]) notifyLocked() {
+	if v.mu.closed {
+		return
+	}
 	if ch := v.mu.updated; ch != nil {
 		close(ch)
 	}
 	v.mu.updated = make(chan struct{})
+	v.mu.version++
+	v.wakeLocked()
+}
+
+// wakeLocked fires and discards the entries of any Aggregations that
+// are waiting for the Var to change.
+func (v *Var[T]) wakeLocked() {
+	for entry := range v.mu.wakers {
+		entry.fire()
+	}
+	clear(v.mu.wakers)
 }
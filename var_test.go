@@ -5,7 +5,10 @@
 package notify
 
 import (
+	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -40,6 +43,7 @@ func TestVar(t *testing.T) {
 	current, ch2 := v.Get()
 	r.Equal(1, current)
 	r.NotEqual(ch, ch2)
+	r.Equal(1, v.Load())
 
 	// Read-locked callback.
 	unchangedCh, err := v.Peek(func(value int) error {
@@ -117,6 +121,308 @@ func TestVar(t *testing.T) {
 	r.Equal(ch4a, ch4b)
 }
 
+func TestClose(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(1)
+	_, ch := v.Get()
+	r.False(v.Closed())
+
+	v.Close()
+	v.Close() // No-op.
+	r.True(v.Closed())
+	r.True(Changed(ch))
+
+	// Writes are discarded and Get always returns a closed channel.
+	v.Set(2)
+	v.Notify()
+	_, _, err := v.Update(func(int) (int, error) { return 3, nil })
+	r.ErrorIs(err, ErrClosed)
+	found, ch := v.Get()
+	r.Equal(1, found)
+	r.True(Changed(ch))
+
+	// A zero-valued Var can be closed.
+	var zero Var[int]
+	zero.Close()
+	_, ch = zero.Get()
+	r.True(Changed(ch))
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(1)
+	_, ch := v.Get()
+
+	r.False(CompareAndSwap(v, 0, 2))
+	r.Equal(1, v.Load())
+	select {
+	case <-ch:
+		r.Fail("channel should be open")
+	default:
+	}
+
+	r.True(CompareAndSwap(v, 1, 2))
+	r.Equal(2, v.Load())
+	select {
+	case <-ch:
+	default:
+		r.Fail("channel should be closed")
+	}
+}
+
+func TestCompareAndSwapVersion(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(1)
+	_, version, _ := v.GetVersioned()
+
+	// Changing the value and changing it back advances the version.
+	v.Set(2)
+	v.Set(1)
+	r.False(v.CompareAndSwapVersion(version, 3))
+	r.Equal(1, v.Load())
+
+	_, version, ch := v.GetVersioned()
+	r.True(v.CompareAndSwapVersion(version, 3))
+	r.Equal(3, v.Load())
+	select {
+	case <-ch:
+	default:
+		r.Fail("channel should be closed")
+	}
+}
+
+func TestGetFresh(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var calls atomic.Int32
+	refresh := func(context.Context) (int, error) {
+		return int(calls.Add(1)), nil
+	}
+
+	// A zero-valued Var is always stale.
+	var v Var[int]
+	found, err := v.GetFresh(ctx, time.Hour, refresh)
+	r.NoError(err)
+	r.Equal(1, found)
+
+	// The refreshed value is now fresh.
+	found, err = v.GetFresh(ctx, time.Hour, refresh)
+	r.NoError(err)
+	r.Equal(1, found)
+	r.Equal(int32(1), calls.Load())
+
+	// Concurrent callers should share a single refresh.
+	var w Var[int]
+	block := make(chan struct{})
+	slow := func(ctx context.Context) (int, error) {
+		<-block
+		return refresh(ctx)
+	}
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := w.GetFresh(ctx, time.Hour, slow)
+			r.NoError(err)
+			r.Equal(2, found)
+		}()
+	}
+	close(block)
+	wg.Wait()
+	r.Equal(int32(2), calls.Load())
+
+	// Errors return the stale value.
+	found, err = v.GetFresh(ctx, 0, func(context.Context) (int, error) {
+		return -1, errors.New("expected")
+	})
+	r.ErrorContains(err, "expected")
+	r.Equal(1, found)
+
+	// A panic does not block later callers.
+	r.Panics(func() {
+		_, _ = v.GetFresh(ctx, 0, func(context.Context) (int, error) {
+			panic("expected")
+		})
+	})
+	found, err = v.GetFresh(ctx, 0, refresh)
+	r.NoError(err)
+	r.Equal(3, found)
+}
+
+func TestSetIfChanged(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(1)
+	_, ch := v.Get()
+	r.False(SetIfChanged(v, 1))
+	r.False(Changed(ch))
+
+	r.True(SetIfChanged(v, 2))
+	r.True(Changed(ch))
+	r.Equal(2, v.Load())
+}
+
+func TestUpdateContext(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	v := VarOf(1)
+	current, _, err := v.UpdateContext(ctx, func(_ context.Context, old int) (int, error) {
+		return old + 1, nil
+	})
+	r.NoError(err)
+	r.Equal(2, current)
+
+	// Cancellation during the callback aborts the update.
+	_, ch := v.Get()
+	current, _, err = v.UpdateContext(ctx, func(ctx context.Context, old int) (int, error) {
+		cancel()
+		return old + 1, nil
+	})
+	r.ErrorIs(err, context.Canceled)
+	r.Equal(2, current)
+	r.False(Changed(ch))
+
+	// The callback is not invoked if the context is already done.
+	_, _, err = v.UpdateContext(ctx, func(context.Context, int) (int, error) {
+		r.Fail("should not be called")
+		return 0, nil
+	})
+	r.ErrorIs(err, context.Canceled)
+}
+
+func TestUpdateIf(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(1)
+	_, ch := v.Get()
+
+	current, unchanged, applied := v.UpdateIf(func(old int) (int, bool) {
+		return old + 1, false
+	})
+	r.False(applied)
+	r.Equal(1, current)
+	r.Equal(ch, unchanged)
+	r.False(Changed(ch))
+
+	current, _, applied = v.UpdateIf(func(old int) (int, bool) {
+		return old + 1, true
+	})
+	r.True(applied)
+	r.Equal(2, current)
+	r.True(Changed(ch))
+
+	v.Close()
+	_, _, applied = v.UpdateIf(func(old int) (int, bool) {
+		return old + 1, true
+	})
+	r.False(applied)
+}
+
+func TestVersioned(t *testing.T) {
+	r := require.New(t)
+
+	var v Var[int]
+	current, version, ch := v.GetVersioned()
+	r.Zero(current)
+	r.Zero(version)
+	r.NotNil(ch)
+
+	version, ch2 := v.SetVersioned(1)
+	r.Equal(uint64(1), version)
+	r.NotEqual(ch, ch2)
+
+	current, version, _, err := v.UpdateVersioned(func(old int) (int, error) {
+		return old + 1, nil
+	})
+	r.NoError(err)
+	r.Equal(2, current)
+	r.Equal(uint64(2), version)
+
+	// No-op updates do not advance the version.
+	_, version, _, err = v.UpdateVersioned(func(int) (int, error) {
+		return 0, ErrNoUpdate
+	})
+	r.NoError(err)
+	r.Equal(uint64(2), version)
+
+	v.Notify()
+	current, version, _ = v.GetVersioned()
+	r.Equal(2, current)
+	r.Equal(uint64(3), version)
+}
+
+func TestWait(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	go func() {
+		for i := 1; i <= 10; i++ {
+			v.Set(i)
+		}
+	}()
+	found, err := v.Reader().Wait(ctx, func(value int) bool { return value > 5 })
+	r.NoError(err)
+	r.Greater(found, 5)
+
+	// Closure interrupts the wait.
+	v.Close()
+	_, err = v.Wait(ctx, func(value int) bool { return value < 0 })
+	r.ErrorIs(err, ErrClosed)
+
+	// Context cancellation interrupts the wait.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	var w Var[int]
+	_, err = w.Wait(canceled, func(value int) bool { return value < 0 })
+	r.ErrorIs(err, context.Canceled)
+}
+
+func TestWatch(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	v := VarOf(0)
+	ch := v.Reader().Watch(ctx)
+	r.Equal(0, <-ch)
+
+	// Intermediate values are conflated.
+	for i := 1; i <= 10; i++ {
+		v.Set(i)
+	}
+	for found := range ch {
+		if found == 10 {
+			break
+		}
+	}
+
+	// The final value is delivered before the channel is closed.
+	v.Set(11)
+	v.Close()
+	var last int
+	for found := range ch {
+		last = found
+	}
+	r.Equal(11, last)
+
+	// Context cancellation closes the channel.
+	canceled, cancel := context.WithCancel(ctx)
+	ch = VarOf(0).Watch(canceled)
+	cancel()
+	for range ch {
+	}
+}
+
 func TestVarOf(t *testing.T) {
 	r := require.New(t)
 
@@ -129,3 +435,10 @@ func TestVarOf(t *testing.T) {
 	default:
 	}
 }
+
+func TestVarLoadAllocs(t *testing.T) {
+	r := require.New(t)
+
+	v := VarOf(1)
+	r.Zero(testing.AllocsPerRun(100, func() { _ = v.Load() }))
+}